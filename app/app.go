@@ -8,40 +8,153 @@ import (
 
 	"github.com/CoreumFoundation/coreum/v5/pkg/client"
 	"github.com/CoreumFoundation/coreum/v5/pkg/config"
+	"github.com/CoreumFoundation/faucet/client/coreum"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
 )
 
 // App implements core functionality.
 type App struct {
-	clientCtx      client.Context
-	batcher        Batcher
-	transferAmount sdk.Coin
-	network        config.NetworkConfig
+	clientCtx client.Context
+	batcher   Batcher
+	ledger    Ledger
+	nodes     NodePool
+	amounts   *transferAmounts
+	network   config.NetworkConfig
+	limiter   AddressLimiter
 }
 
 // New returns a new instance of the App.
-func New(clientCtx client.Context, batcher Batcher, network config.NetworkConfig, transferAmount sdk.Coin) App {
+// transferAmount is sent when the caller doesn't ask for specific denom and amount, maxTransferAmount defines
+// the denoms the caller may ask for and the maximum amount of each of them.
+func New(
+	clientCtx client.Context,
+	batcher Batcher,
+	ledger Ledger,
+	nodes NodePool,
+	network config.NetworkConfig,
+	transferAmount sdk.Coins,
+	maxTransferAmount sdk.Coins,
+	limiter AddressLimiter,
+) App {
 	return App{
-		clientCtx:      clientCtx,
-		batcher:        batcher,
-		network:        network,
-		transferAmount: transferAmount,
+		clientCtx: clientCtx,
+		batcher:   batcher,
+		ledger:    ledger,
+		nodes:     nodes,
+		network:   network,
+		amounts:   newTransferAmounts(transferAmount, maxTransferAmount),
+		limiter:   limiter,
 	}
 }
 
+// SetTransferAmounts replaces the transfer amounts while running, the requests already accepted are not affected.
+func (a App) SetTransferAmounts(transferAmount, maxTransferAmount sdk.Coins) {
+	a.amounts.set(transferAmount, maxTransferAmount)
+}
+
 // Batcher indicates the required functionality to connect to coreum blockchain.
 type Batcher interface {
-	SendToken(ctx context.Context, destAddress sdk.AccAddress, amount sdk.Coin) (string, error)
+	SendTokenAsync(
+		ctx context.Context,
+		destAddress sdk.AccAddress,
+		amount sdk.Coins,
+		register coreum.RegisterFunc,
+		done coreum.DoneFunc,
+	) error
+}
+
+// Ledger indicates the required functionality to record the fund requests.
+type Ledger interface {
+	Create(record ledger.Record) (ledger.Record, error)
+	Update(id string, update func(record *ledger.Record)) (ledger.Record, error)
+	UpdateMany(ids []string, update func(record *ledger.Record)) error
+	Get(id string) (ledger.Record, error)
+	Query(filter ledger.Filter, cursor string, limit int) ([]ledger.Record, string, error)
+}
+
+// AddressLimiter limits the number of fund requests sent to the same destination address.
+type AddressLimiter interface {
+	Reserve(key string) bool
+	Refund(key string)
+}
+
+// NodePool indicates the required functionality to communicate with the coreum nodes.
+type NodePool interface {
+	ClientContext() client.Context
+	Statuses() []coreum.NodeStatus
+}
+
+// Nodes returns the state of the coreum nodes used by the faucet.
+func (a App) Nodes() []coreum.NodeStatus {
+	return a.nodes.Statuses()
+}
+
+// GiveFunds gives funds to people asking for it. Denom and amount are optional, if they are empty the default
+// transfer amount is sent.
+func (a App) GiveFunds(ctx context.Context, address, denom, amount string) (string, error) {
+	sdkAddr, coins, err := a.validateFundRequest(address, denom, amount)
+	if err != nil {
+		return "", err
+	}
+	if err := a.reserveAddressLimit(sdkAddr); err != nil {
+		return "", err
+	}
+
+	txHash, err := a.sendToken(ctx, sdkAddr, coins)
+	if err != nil {
+		a.refundAddressLimit(sdkAddr, err)
+		return "", transferError(err)
+	}
+
+	return txHash, nil
+}
+
+// reserveAddressLimit rejects the request if the destination address has already used its rate limit, so rotating
+// IPs doesn't allow to drain the faucet into one address. Request is counted before it is enqueued, so concurrent
+// requests can't exceed the limit.
+func (a App) reserveAddressLimit(address sdk.AccAddress) error {
+	if !a.limiter.Reserve(address.String()) {
+		return errors.Wrapf(ErrAddressRateLimited, "address %q has already used its rate limit", address)
+	}
+	return nil
+}
+
+// refundAddressLimit gives back the rate limit of the address if the request is known not to have been sent.
+// Requests which might have been sent, or which are still being processed after the caller gave up, keep using it.
+func (a App) refundAddressLimit(address sdk.AccAddress, err error) {
+	if errors.Is(err, coreum.ErrOutcomeUnknown) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	a.limiter.Refund(address.String())
 }
 
-// GiveFunds gives funds to people asking for it.
-func (a App) GiveFunds(ctx context.Context, address string) (string, error) {
+// transferError maps the error returned by the batcher to the app error.
+func transferError(err error) error {
+	switch {
+	case errors.Is(err, coreum.ErrDuplicateRequest):
+		return errors.Wrapf(ErrDuplicateRequest, "err:%s", err)
+	case errors.Is(err, coreum.ErrQueueFull):
+		return errors.Wrapf(ErrQueueFull, "err:%s", err)
+	case errors.Is(err, coreum.ErrShuttingDown):
+		return errors.Wrapf(ErrShuttingDown, "err:%s", err)
+	case errors.Is(err, coreum.ErrNoFundingAccount):
+		return errors.Wrapf(ErrNoFundingAccount, "err:%s", err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return errors.Wrapf(ErrRequestCanceled, "err:%s", err)
+	default:
+		return errors.Wrapf(ErrUnableToTransferToken, "err:%s", err)
+	}
+}
+
+func (a App) validateFundRequest(address, denom, amount string) (sdk.AccAddress, sdk.Coins, error) {
 	prefix, sdkAddr, err := parseAddress(address)
 	if err != nil {
-		return "", errors.Wrapf(ErrInvalidAddressFormat, "err:%s", err)
+		return nil, nil, errors.Wrapf(ErrInvalidAddressFormat, "err:%s", err)
 	}
 
 	if prefix != a.network.Provider.GetAddressPrefix() {
-		return "", errors.Wrapf(
+		return nil, nil, errors.Wrapf(
 			ErrAddressPrefixUnsupported,
 			"account prefix (%s) does not match expected prefix (%s)",
 			prefix,
@@ -49,10 +162,63 @@ func (a App) GiveFunds(ctx context.Context, address string) (string, error) {
 		)
 	}
 
-	txHash, err := a.batcher.SendToken(ctx, sdkAddr, a.transferAmount)
+	coins, err := a.transferAmountFor(denom, amount)
 	if err != nil {
-		return "", errors.Wrapf(ErrUnableToTransferToken, "err:%s", err)
+		return nil, nil, err
 	}
 
-	return txHash, nil
+	return sdkAddr, coins, nil
+}
+
+// sendToken records the request in the ledger, passes it to the batcher and waits for the result.
+func (a App) sendToken(ctx context.Context, destAddress sdk.AccAddress, amount sdk.Coins) (string, error) {
+	type result struct {
+		res coreum.TransferResult
+		err error
+	}
+	resChan := make(chan result, 1)
+	_, err := a.enqueue(ctx, destAddress, amount, func(res coreum.TransferResult, err error) {
+		resChan <- result{res: res, err: err}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	select {
+	case res := <-resChan:
+		return res.res.TxHash, res.err
+	case <-ctx.Done():
+		return "", errors.Wrap(ctx.Err(), "request aborted")
+	}
+}
+
+// enqueue records the request in the ledger and passes it to the batcher. Request is recorded once there is room
+// for it in the queue, so the rejected requests are not written to the ledger. Progress of the request is recorded
+// by the function returned by RecordProgress. Done function is called once the request is processed.
+func (a App) enqueue(
+	ctx context.Context,
+	destAddress sdk.AccAddress,
+	amount sdk.Coins,
+	done coreum.DoneFunc,
+) (ledger.Record, error) {
+	var record ledger.Record
+	err := a.batcher.SendTokenAsync(
+		ctx,
+		destAddress,
+		amount,
+		func() (string, error) {
+			var err error
+			record, err = a.createRecord(ctx, destAddress, amount)
+			return record.ID, err
+		},
+		func(res coreum.TransferResult, err error) {
+			a.recordResult(ctx, record.ID, res, err)
+			done(res, err)
+		},
+	)
+	if err != nil {
+		return ledger.Record{}, err
+	}
+
+	return record, nil
 }
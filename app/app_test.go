@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config/constant"
+	"github.com/CoreumFoundation/faucet/client/coreum"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+	"github.com/CoreumFoundation/faucet/pkg/limiter"
+)
+
+// mockBatcher rejects the request with enqueueErr, or completes it with transferErr. If transferErr is nil,
+// the request is never completed.
+type mockBatcher struct {
+	enqueueErr  error
+	transferErr error
+}
+
+func (b mockBatcher) SendTokenAsync(
+	_ context.Context,
+	_ sdk.AccAddress,
+	_ sdk.Coins,
+	register coreum.RegisterFunc,
+	done coreum.DoneFunc,
+) error {
+	if b.enqueueErr != nil {
+		return b.enqueueErr
+	}
+	if _, err := register(); err != nil {
+		return err
+	}
+	if b.transferErr != nil {
+		go done(coreum.TransferResult{}, b.transferErr)
+	}
+	return nil
+}
+
+func TestGiveFundsRefundsAddressLimit(t *testing.T) {
+	network, err := config.NetworkConfigByChainID(constant.ChainIDDev)
+	require.NoError(t, err)
+	sdkAddr := sdk.AccAddress(make([]byte, 20))
+	address, err := bech32.ConvertAndEncode(constant.AddressPrefixDev, sdkAddr)
+	require.NoError(t, err)
+	amount := sdk.NewCoins(sdk.NewInt64Coin(network.Denom(), 100))
+
+	testCases := []struct {
+		name        string
+		batcher     mockBatcher
+		canceled    bool
+		expectedErr error
+		refunded    bool
+	}{
+		{
+			name:        "queue full",
+			batcher:     mockBatcher{enqueueErr: errors.WithStack(coreum.ErrQueueFull)},
+			expectedErr: ErrQueueFull,
+			refunded:    true,
+		},
+		{
+			name:        "shutting down",
+			batcher:     mockBatcher{enqueueErr: errors.WithStack(coreum.ErrShuttingDown)},
+			expectedErr: ErrShuttingDown,
+			refunded:    true,
+		},
+		{
+			name:        "duplicate rejected",
+			batcher:     mockBatcher{transferErr: errors.WithStack(coreum.ErrDuplicateRequest)},
+			expectedErr: ErrDuplicateRequest,
+			refunded:    true,
+		},
+		{
+			name:        "transfer failed",
+			batcher:     mockBatcher{transferErr: errors.Wrap(cosmoserrors.ErrInsufficientFunds, "transaction failed")},
+			expectedErr: ErrUnableToTransferToken,
+			refunded:    true,
+		},
+		{
+			name:        "outcome unknown",
+			batcher:     mockBatcher{transferErr: errors.WithStack(coreum.ErrOutcomeUnknown)},
+			expectedErr: ErrUnableToTransferToken,
+			refunded:    false,
+		},
+		{
+			name:        "caller canceled",
+			canceled:    true,
+			expectedErr: ErrRequestCanceled,
+			refunded:    false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requireT := require.New(t)
+
+			store, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.db"))
+			requireT.NoError(err)
+			t.Cleanup(func() {
+				requireT.NoError(store.Close())
+			})
+			addressLimiter := limiter.NewWeightedWindowLimiter(1, time.Hour)
+			a := New(client.Context{}, tc.batcher, store, nil, network, amount, amount, addressLimiter)
+
+			ctx := t.Context()
+			if tc.canceled {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				cancel()
+			}
+			_, err = a.GiveFunds(ctx, address, "", "")
+			requireT.ErrorIs(err, tc.expectedErr)
+
+			// the limit is available again only if it has been refunded
+			requireT.Equal(tc.refunded, addressLimiter.Reserve(sdkAddr.String()))
+		})
+	}
+}
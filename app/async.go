@@ -0,0 +1,46 @@
+package app
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/CoreumFoundation/faucet/client/coreum"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+)
+
+// GiveFundsAsync validates the fund request and enqueues it without waiting for the transfer.
+// It returns the ID of the job which might be used to track the request.
+func (a App) GiveFundsAsync(ctx context.Context, address, denom, amount string) (string, error) {
+	sdkAddr, coins, err := a.validateFundRequest(address, denom, amount)
+	if err != nil {
+		return "", err
+	}
+	if err := a.reserveAddressLimit(sdkAddr); err != nil {
+		return "", err
+	}
+
+	record, err := a.enqueue(ctx, sdkAddr, coins, func(_ coreum.TransferResult, err error) {
+		if err != nil {
+			a.refundAddressLimit(sdkAddr, err)
+		}
+	})
+	if err != nil {
+		a.refundAddressLimit(sdkAddr, err)
+		return "", transferError(err)
+	}
+
+	return record.ID, nil
+}
+
+// Job returns the state of the fund request.
+func (a App) Job(id string) (ledger.Record, error) {
+	record, err := a.ledger.Get(id)
+	if err != nil {
+		if errors.Is(err, ledger.ErrNotFound) {
+			return ledger.Record{}, errors.Wrapf(ErrJobNotFound, "id: %s", id)
+		}
+		return ledger.Record{}, err
+	}
+	return record, nil
+}
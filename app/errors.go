@@ -7,4 +7,15 @@ var (
 	ErrInvalidAddressFormat     = errors.New("invalid address format")
 	ErrAddressPrefixUnsupported = errors.New("address prefix is not supported by this chain")
 	ErrUnableToTransferToken    = errors.New("unable to transfer tokens")
+	ErrDenomNotAllowed          = errors.New("denom is not allowed")
+	ErrInvalidAmount            = errors.New("invalid amount")
+	ErrAmountExceedsLimit       = errors.New("requested amount exceeds the limit")
+	ErrInvalidHistoryQuery      = errors.New("invalid history query")
+	ErrJobNotFound              = errors.New("job not found")
+	ErrDuplicateRequest         = errors.New("request for the same address is already being processed")
+	ErrQueueFull                = errors.New("too many requests are waiting to be processed")
+	ErrShuttingDown             = errors.New("faucet is shutting down")
+	ErrAddressRateLimited       = errors.New("rate limit exhausted for the address")
+	ErrNoFundingAccount         = errors.New("no funding account is available")
+	ErrRequestCanceled          = errors.New("request canceled")
 )
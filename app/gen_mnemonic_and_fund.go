@@ -27,9 +27,10 @@ func (a App) GenMnemonicAndFund(ctx context.Context) (GenMnemonicAndFundResult,
 	if err != nil {
 		return GenMnemonicAndFundResult{}, errors.Wrapf(ErrUnableToTransferToken, "err:%s", err)
 	}
-	txHash, err := a.batcher.SendToken(ctx, sdkAddr, a.transferAmount)
+	transferAmount, _ := a.amounts.get()
+	txHash, err := a.sendToken(ctx, sdkAddr, transferAmount)
 	if err != nil {
-		return GenMnemonicAndFundResult{}, errors.Wrapf(ErrUnableToTransferToken, "err:%s", err)
+		return GenMnemonicAndFundResult{}, transferError(err)
 	}
 
 	return GenMnemonicAndFundResult{
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+)
+
+// MaxHistoryLimit is the maximum number of records returned by a single History call.
+const MaxHistoryLimit = 500
+
+// History returns the page of past fund requests matching the filter, starting from the newest one.
+// The returned cursor should be passed to get the next page, it is empty if there are no more records.
+func (a App) History(filter ledger.Filter, cursor string, limit int) ([]ledger.Record, string, error) {
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, "", errors.Wrapf(ErrInvalidHistoryQuery, "unknown status %q", filter.Status)
+	}
+	if limit <= 0 || limit > MaxHistoryLimit {
+		return nil, "", errors.Wrapf(ErrInvalidHistoryQuery, "limit must be between 1 and %d", MaxHistoryLimit)
+	}
+
+	records, nextCursor, err := a.ledger.Query(filter, cursor, limit)
+	if err != nil {
+		if errors.Is(err, ledger.ErrInvalidCursor) {
+			return nil, "", errors.Wrapf(ErrInvalidHistoryQuery, "err:%s", err)
+		}
+		return nil, "", err
+	}
+	return records, nextCursor, nil
+}
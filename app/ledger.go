@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+	"github.com/CoreumFoundation/faucet/client/coreum"
+	faucethttp "github.com/CoreumFoundation/faucet/pkg/http"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+)
+
+func (a App) createRecord(ctx context.Context, destAddress sdk.AccAddress, amount sdk.Coins) (ledger.Record, error) {
+	requestInfo := faucethttp.RequestInfoFromContext(ctx)
+	record := ledger.Record{
+		RequestID: requestInfo.RequestID,
+		Address:   destAddress.String(),
+		Coins:     amount.String(),
+		Status:    ledger.StatusQueued,
+	}
+	if requestInfo.ClientIP != nil {
+		record.ClientIP = requestInfo.ClientIP.String()
+	}
+
+	record, err := a.ledger.Create(record)
+	if err != nil {
+		return ledger.Record{}, errors.Wrap(err, "unable to record the request in the ledger")
+	}
+	return record, nil
+}
+
+var stageStatuses = map[coreum.Stage]ledger.Status{
+	coreum.StageBatched:   ledger.StatusBatched,
+	coreum.StageBroadcast: ledger.StatusBroadcast,
+}
+
+// RecordProgress returns the function storing the current stage of the batches in the ledger. Records of the whole
+// batch are updated in a single transaction.
+func RecordProgress(store Ledger) coreum.ProgressFunc {
+	return func(ctx context.Context, stage coreum.Stage, res coreum.TransferResult, ids []string) {
+		if len(ids) == 0 {
+			return
+		}
+		err := store.UpdateMany(ids, func(record *ledger.Record) {
+			record.Status = stageStatuses[stage]
+			record.TxHash = res.TxHash
+			if !res.FundingAddress.Empty() {
+				record.FundingAddress = res.FundingAddress.String()
+			}
+		})
+		if err != nil {
+			logger.Get(ctx).Error("Unable to record the progress of the transfers in the ledger",
+				zap.Strings("ids", ids),
+				zap.String("stage", string(stage)),
+				zap.Error(err))
+		}
+	}
+}
+
+// recordResult stores the outcome of the transfer in the ledger. The transfer has been already executed
+// at this point so the failure is only logged.
+func (a App) recordResult(ctx context.Context, id string, res coreum.TransferResult, transferErr error) {
+	_, err := a.ledger.Update(id, func(record *ledger.Record) {
+		record.TxHash = res.TxHash
+		if !res.FundingAddress.Empty() {
+			record.FundingAddress = res.FundingAddress.String()
+		}
+		if errors.Is(transferErr, coreum.ErrOutcomeUnknown) {
+			record.Status = ledger.StatusUnknown
+			record.Error = transferErr.Error()
+			return
+		}
+		if transferErr != nil {
+			record.Status = ledger.StatusFailed
+			record.Error = transferErr.Error()
+			return
+		}
+		record.Status = ledger.StatusCommitted
+	})
+	if err != nil {
+		logger.Get(ctx).Error("Unable to record the result of the transfer in the ledger",
+			zap.String("id", id),
+			zap.String("txHash", res.TxHash),
+			zap.Error(err))
+	}
+}
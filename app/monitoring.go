@@ -3,6 +3,8 @@ package app
 import (
 	"context"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -14,23 +16,40 @@ import (
 
 	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
 	"github.com/CoreumFoundation/coreum-tools/pkg/parallel"
-	"github.com/CoreumFoundation/coreum/v5/pkg/client"
 	faucethttp "github.com/CoreumFoundation/faucet/pkg/http"
 )
 
-// RunMonitoring runs monitoring service.
+// BalanceReporter is notified about the balances of the funding accounts.
+type BalanceReporter interface {
+	ReportBalance(address sdk.AccAddress, balance sdk.Coin)
+}
+
+// FundingAccounts provides the addresses of the funding accounts, which might change while running.
+type FundingAccounts interface {
+	FundingAddresses() []sdk.AccAddress
+}
+
+// RunMonitoring runs monitoring service. Collectors are registered in addition to the metrics of the app.
 func RunMonitoring(
 	ctx context.Context,
 	listenAddress string,
-	clientCtx client.Context,
-	addresses []sdk.AccAddress,
+	nodes NodePool,
+	fundingAccounts FundingAccounts,
 	denom string,
+	balanceReporter BalanceReporter,
+	collectors ...prometheus.Collector,
 ) error {
 	metricRecorder := newRecorder()
 	registry := metricRecorder.Registry()
+	registry.MustRegister(collectors...)
 
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	metricsHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	mux.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// balances of the accounts retired since the last probe are not exported
+		metricRecorder.ForgetBalances(fundingAccounts.FundingAddresses())
+		metricsHandler.ServeHTTP(w, req)
+	}))
 	server := &http.Server{Addr: listenAddress, Handler: mux}
 
 	return parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
@@ -53,8 +72,10 @@ func RunMonitoring(
 		})
 		spawn("balances", parallel.Fail, func(ctx context.Context) error {
 			log := logger.Get(ctx)
-			bankClient := banktypes.NewQueryClient(clientCtx)
 			for {
+				bankClient := banktypes.NewQueryClient(nodes.ClientContext())
+				addresses := fundingAccounts.FundingAddresses()
+				metricRecorder.ForgetBalances(addresses)
 				for _, addr := range addresses {
 					resp, err := bankClient.Balance(ctx, &banktypes.QueryBalanceRequest{
 						Address: addr.String(),
@@ -64,7 +85,8 @@ func RunMonitoring(
 						log.Error("Error occurred while probing balance", zap.Error(err))
 						continue
 					}
-					metricRecorder.Balance(addr).Set(float64(resp.Balance.Amount.Uint64()))
+					metricRecorder.SetBalance(addr, float64(resp.Balance.Amount.Uint64()))
+					balanceReporter.ReportBalance(addr, *resp.Balance)
 				}
 
 				select {
@@ -83,6 +105,10 @@ func RunMonitoring(
 type recorder struct {
 	registry     *prometheus.Registry
 	balanceGauge *prometheus.GaugeVec
+
+	mu sync.Mutex
+	// balanceAddresses are the addresses the balance gauges exist for.
+	balanceAddresses map[string]bool
 }
 
 // newRecorder returns a new instance of the recorder.
@@ -96,8 +122,9 @@ func newRecorder() *recorder {
 	registry.MustRegister(balanceGauge)
 
 	return &recorder{
-		registry:     registry,
-		balanceGauge: balanceGauge,
+		registry:         registry,
+		balanceGauge:     balanceGauge,
+		balanceAddresses: map[string]bool{},
 	}
 }
 
@@ -106,9 +133,24 @@ func (r *recorder) Registry() *prometheus.Registry {
 	return r.registry
 }
 
-// Balance returns gauge for measuring the balance of the address.
-func (r *recorder) Balance(address sdk.AccAddress) prometheus.Gauge {
-	return r.balanceGauge.With(prometheus.Labels{
-		"address": address.String(),
-	})
+// SetBalance sets the balance gauge of the address.
+func (r *recorder) SetBalance(address sdk.AccAddress, balance float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.balanceAddresses[address.String()] = true
+	r.balanceGauge.WithLabelValues(address.String()).Set(balance)
+}
+
+// ForgetBalances removes the gauges of the addresses which are no longer funding accounts.
+func (r *recorder) ForgetBalances(current []sdk.AccAddress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for address := range r.balanceAddresses {
+		if !slices.ContainsFunc(current, func(a sdk.AccAddress) bool { return a.String() == address }) {
+			r.balanceGauge.DeleteLabelValues(address)
+			delete(r.balanceAddresses, address)
+		}
+	}
 }
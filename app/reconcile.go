@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sdkerrors "cosmossdk.io/errors"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+	"github.com/CoreumFoundation/coreum-tools/pkg/parallel"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+)
+
+const (
+	// reconcileTxTimeout is how long the tx recorded in the ledger is looked for on chain during reconciliation.
+	reconcileTxTimeout = 30 * time.Second
+	reconcilePageSize  = 100
+	// reconcileWorkers is the number of requests reconciled in parallel.
+	reconcileWorkers = 10
+	// reconcileMaxAttempts is how many times the tx of the request with unknown outcome is looked for on chain.
+	// Once it is exhausted, the outcome stays unknown and the request is not checked again.
+	reconcileMaxAttempts = 3
+)
+
+// unfinishedStatuses are the statuses of the requests whose outcome wasn't recorded before the faucet stopped.
+var unfinishedStatuses = []ledger.Status{
+	ledger.StatusQueued,
+	ledger.StatusBatched,
+	ledger.StatusBroadcast,
+	ledger.StatusUnknown,
+}
+
+// TxAwaiter indicates the required functionality to check whether the tx has been committed.
+type TxAwaiter interface {
+	AwaitTx(ctx context.Context, txHash string) error
+}
+
+// Reconcile resolves the outcome of the requests created before startedAt which were still being processed when
+// the faucet stopped. Requests never batched are marked as failed, the broadcast ones are checked on chain.
+// Requests which might have been broadcast but can't be found on chain are marked as unknown, and they are
+// checked again on the next start, up to reconcileMaxAttempts times. Unknown requests without the tx hash
+// are never checked again, as there is nothing to look for.
+func Reconcile(ctx context.Context, store Ledger, txs TxAwaiter, startedAt time.Time) error {
+	// all the records are queried before any is updated, so the ones marked as unknown are not checked twice
+	var records []ledger.Record
+	for _, status := range unfinishedStatuses {
+		statusRecords, err := queryAll(store, ledger.Filter{Status: status, To: startedAt})
+		if err != nil {
+			return err
+		}
+		for _, record := range statusRecords {
+			if isReconcilable(record) {
+				records = append(records, record)
+			}
+		}
+	}
+
+	return parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
+		recordCh := make(chan ledger.Record)
+		spawn("records", parallel.Continue, func(ctx context.Context) error {
+			defer close(recordCh)
+			for _, record := range records {
+				select {
+				case recordCh <- record:
+				case <-ctx.Done():
+					return errors.WithStack(ctx.Err())
+				}
+			}
+			return nil
+		})
+		for i := range reconcileWorkers {
+			spawn(fmt.Sprintf("worker-%d", i), parallel.Continue, func(ctx context.Context) error {
+				for record := range recordCh {
+					if err := reconcileRecord(ctx, store, txs, record); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+func reconcileRecord(ctx context.Context, store Ledger, txs TxAwaiter, record ledger.Record) error {
+	status, recordErr := recordOutcome(ctx, txs, record)
+	if ctx.Err() != nil {
+		return errors.WithStack(ctx.Err())
+	}
+	_, err := store.Update(record.ID, func(record *ledger.Record) {
+		record.Status = status
+		record.Error = recordErr
+		if status == ledger.StatusUnknown {
+			record.ReconcileAttempts++
+		}
+	})
+	if err != nil {
+		return err
+	}
+	logger.Get(ctx).Info("Request reconciled",
+		zap.String("id", record.ID),
+		zap.String("txHash", record.TxHash),
+		zap.String("status", string(status)))
+	return nil
+}
+
+// isReconcilable tells if the outcome of the request might still be resolved.
+func isReconcilable(record ledger.Record) bool {
+	if record.Status != ledger.StatusUnknown {
+		return true
+	}
+	return record.TxHash != "" && record.ReconcileAttempts < reconcileMaxAttempts
+}
+
+func recordOutcome(ctx context.Context, txs TxAwaiter, record ledger.Record) (ledger.Status, string) {
+	if record.Status == ledger.StatusQueued {
+		return ledger.StatusFailed, "faucet stopped before the request was sent"
+	}
+	// hash is recorded after the broadcast, so the batched request might have been sent without it
+	if record.TxHash == "" {
+		return ledger.StatusUnknown, "faucet stopped before the transaction hash was recorded"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reconcileTxTimeout)
+	defer cancel()
+
+	err := txs.AwaitTx(ctx, record.TxHash)
+	if err == nil {
+		return ledger.StatusCommitted, ""
+	}
+	// only the tx executed with an error code is known to be failed, the one not found might still be committed
+	var abciErr *sdkerrors.Error
+	if errors.As(err, &abciErr) {
+		return ledger.StatusFailed, errors.Wrap(err, "transaction failed").Error()
+	}
+	return ledger.StatusUnknown, errors.Wrap(err, "transaction not found").Error()
+}
+
+func queryAll(store Ledger, filter ledger.Filter) ([]ledger.Record, error) {
+	var all []ledger.Record
+	cursor := ""
+	for {
+		records, nextCursor, err := store.Query(filter, cursor, reconcilePageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, records...)
+		if nextCursor == "" {
+			return all, nil
+		}
+		cursor = nextCursor
+	}
+}
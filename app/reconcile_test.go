@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+)
+
+type mockTxAwaiter map[string]error
+
+func (m mockTxAwaiter) AwaitTx(_ context.Context, txHash string) error {
+	return m[txHash]
+}
+
+func TestReconcile(t *testing.T) {
+	requireT := require.New(t)
+
+	store, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.db"))
+	requireT.NoError(err)
+	t.Cleanup(func() {
+		requireT.NoError(store.Close())
+	})
+
+	create := func(status ledger.Status, txHash string) string {
+		record, err := store.Create(ledger.Record{Status: status, TxHash: txHash})
+		requireT.NoError(err)
+		return record.ID
+	}
+	txs := mockTxAwaiter{
+		"failed":    errors.Wrap(cosmoserrors.ErrInsufficientFunds, "transaction failed"),
+		"not-found": errors.New("transaction hasn't been included in a block yet"),
+	}
+	queued := create(ledger.StatusQueued, "")
+	batched := create(ledger.StatusBatched, "")
+	committed := create(ledger.StatusBroadcast, "committed")
+	failed := create(ledger.StatusUnknown, "failed")
+	notFound := create(ledger.StatusBroadcast, "not-found")
+	done := create(ledger.StatusCommitted, "done")
+	startedAt := time.Now().UTC()
+	// request received after the startup is being processed by the running faucet
+	current := create(ledger.StatusQueued, "")
+
+	requireT.NoError(Reconcile(logger.WithLogger(t.Context(), zaptest.NewLogger(t)), store, txs, startedAt))
+
+	expected := map[string]ledger.Status{
+		queued:    ledger.StatusFailed,
+		batched:   ledger.StatusUnknown,
+		committed: ledger.StatusCommitted,
+		failed:    ledger.StatusFailed,
+		notFound:  ledger.StatusUnknown,
+		done:      ledger.StatusCommitted,
+		current:   ledger.StatusQueued,
+	}
+	for id, status := range expected {
+		record, err := store.Get(id)
+		requireT.NoError(err)
+		requireT.Equal(status, record.Status, record.TxHash)
+	}
+
+	// tx not found is looked for until the attempts are exhausted, request without tx hash is never checked again
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	recorder := &recordingTxAwaiter{txs: txs}
+	for range reconcileMaxAttempts + 1 {
+		requireT.NoError(Reconcile(ctx, store, recorder, time.Now().UTC()))
+	}
+	requireT.Equal([]string{"not-found", "not-found"}, recorder.awaited)
+	record, err := store.Get(notFound)
+	requireT.NoError(err)
+	requireT.Equal(ledger.StatusUnknown, record.Status)
+	requireT.Equal(reconcileMaxAttempts, record.ReconcileAttempts)
+}
+
+// recordingTxAwaiter records the hashes of the txs looked for.
+type recordingTxAwaiter struct {
+	txs     mockTxAwaiter
+	mu      sync.Mutex
+	awaited []string
+}
+
+func (r *recordingTxAwaiter) AwaitTx(ctx context.Context, txHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.awaited = append(r.awaited, txHash)
+	return r.txs.AwaitTx(ctx, txHash)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"strings"
+	"sync"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pkg/errors"
+)
+
+// transferAmounts are the default and the maximum amounts sent by the faucet, they might be replaced while running.
+type transferAmounts struct {
+	mu                sync.RWMutex
+	transferAmount    sdk.Coins
+	maxTransferAmount sdk.Coins
+}
+
+func newTransferAmounts(transferAmount, maxTransferAmount sdk.Coins) *transferAmounts {
+	return &transferAmounts{
+		transferAmount:    transferAmount,
+		maxTransferAmount: maxTransferAmount,
+	}
+}
+
+func (t *transferAmounts) get() (sdk.Coins, sdk.Coins) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.transferAmount, t.maxTransferAmount
+}
+
+func (t *transferAmounts) set(transferAmount, maxTransferAmount sdk.Coins) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.transferAmount = transferAmount
+	t.maxTransferAmount = maxTransferAmount
+}
+
+// transferAmountFor returns the coins to transfer for the denom and amount requested by the caller.
+// If neither denom nor amount is requested, the default transfer amount is returned.
+func (a App) transferAmountFor(denom, amount string) (sdk.Coins, error) {
+	transferAmount, maxTransferAmount := a.amounts.get()
+	denom = strings.TrimSpace(denom)
+	amount = strings.TrimSpace(amount)
+	if denom == "" && amount == "" {
+		return transferAmount, nil
+	}
+	if denom == "" {
+		denom = a.network.Denom()
+	}
+
+	maxAmount := maxTransferAmount.AmountOf(denom)
+	if !maxAmount.IsPositive() {
+		return nil, errors.Wrapf(ErrDenomNotAllowed, "denom %q is not allowed", denom)
+	}
+
+	var requested sdkmath.Int
+	if amount == "" {
+		requested = transferAmount.AmountOf(denom)
+		if !requested.IsPositive() || requested.GT(maxAmount) {
+			requested = maxAmount
+		}
+	} else {
+		var ok bool
+		requested, ok = sdkmath.NewIntFromString(amount)
+		if !ok || !requested.IsPositive() {
+			return nil, errors.Wrapf(ErrInvalidAmount, "amount %q must be a positive integer", amount)
+		}
+	}
+
+	if requested.GT(maxAmount) {
+		return nil, errors.Wrapf(
+			ErrAmountExceedsLimit,
+			"requested amount %s%s exceeds the limit of %s%s",
+			requested,
+			denom,
+			maxAmount,
+			denom,
+		)
+	}
+
+	return sdk.NewCoins(sdk.NewCoin(denom, requested)), nil
+}
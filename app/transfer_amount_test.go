@@ -0,0 +1,123 @@
+package app
+
+import (
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config/constant"
+)
+
+func TestTransferAmountFor(t *testing.T) {
+	network, err := config.NetworkConfigByChainID(constant.ChainIDDev)
+	require.NoError(t, err)
+
+	a := App{
+		network: network,
+		amounts: newTransferAmounts(
+			sdk.NewCoins(
+				sdk.NewCoin(network.Denom(), sdkmath.NewInt(100)),
+				sdk.NewCoin("utoken", sdkmath.NewInt(10)),
+			),
+			sdk.NewCoins(
+				sdk.NewCoin(network.Denom(), sdkmath.NewInt(1000)),
+				sdk.NewCoin("utoken", sdkmath.NewInt(5)),
+				sdk.NewCoin("uother", sdkmath.NewInt(50)),
+			),
+		),
+	}
+
+	testCases := []struct {
+		name          string
+		denom         string
+		amount        string
+		expectedCoins sdk.Coins
+		expectedErr   error
+	}{
+		{
+			name:          "default basket",
+			expectedCoins: a.amounts.transferAmount,
+		},
+		{
+			name:          "amount of network denom",
+			amount:        "500",
+			expectedCoins: sdk.NewCoins(sdk.NewCoin(network.Denom(), sdkmath.NewInt(500))),
+		},
+		{
+			name:          "denom from basket",
+			denom:         network.Denom(),
+			expectedCoins: sdk.NewCoins(sdk.NewCoin(network.Denom(), sdkmath.NewInt(100))),
+		},
+		{
+			name:          "denom with default amount above the limit",
+			denom:         "utoken",
+			expectedCoins: sdk.NewCoins(sdk.NewCoin("utoken", sdkmath.NewInt(5))),
+		},
+		{
+			name:          "denom outside of basket",
+			denom:         "uother",
+			expectedCoins: sdk.NewCoins(sdk.NewCoin("uother", sdkmath.NewInt(50))),
+		},
+		{
+			name:        "denom not allowed",
+			denom:       "unknown",
+			amount:      "1",
+			expectedErr: ErrDenomNotAllowed,
+		},
+		{
+			name:        "amount above the limit",
+			denom:       "uother",
+			amount:      "51",
+			expectedErr: ErrAmountExceedsLimit,
+		},
+		{
+			name:        "negative amount",
+			amount:      "-1",
+			expectedErr: ErrInvalidAmount,
+		},
+		{
+			name:        "malformed amount",
+			amount:      "1.5",
+			expectedErr: ErrInvalidAmount,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requireT := require.New(t)
+			coins, err := a.transferAmountFor(tc.denom, tc.amount)
+			if tc.expectedErr != nil {
+				requireT.ErrorIs(err, tc.expectedErr)
+				return
+			}
+			requireT.NoError(err)
+			requireT.Equal(tc.expectedCoins.String(), coins.String())
+		})
+	}
+}
+
+func TestSetTransferAmounts(t *testing.T) {
+	requireT := require.New(t)
+	network, err := config.NetworkConfigByChainID(constant.ChainIDDev)
+	requireT.NoError(err)
+
+	amount := sdk.NewCoins(sdk.NewCoin(network.Denom(), sdkmath.NewInt(100)))
+	a := New(client.Context{}, nil, nil, nil, network, amount, amount, nil)
+	// copies of the app share the amounts, so the ones held by the server are replaced too
+	server := a
+
+	newAmount := sdk.NewCoins(sdk.NewCoin(network.Denom(), sdkmath.NewInt(200)))
+	newMaxAmount := sdk.NewCoins(sdk.NewCoin(network.Denom(), sdkmath.NewInt(300)))
+	a.SetTransferAmounts(newAmount, newMaxAmount)
+
+	coins, err := server.transferAmountFor("", "")
+	requireT.NoError(err)
+	requireT.Equal(newAmount, coins)
+	coins, err = server.transferAmountFor("", "300")
+	requireT.NoError(err)
+	requireT.Equal(newMaxAmount, coins)
+}
@@ -0,0 +1,121 @@
+package coreum
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	sdkerrors "cosmossdk.io/errors"
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/pkg/errors"
+)
+
+type accountHealth struct {
+	consecutiveFailures int
+	excludedUntil       time.Time
+	lowBalance          bool
+}
+
+// accountTracker tracks the health of the funding accounts. Account is excluded if it fails too many times
+// in a row, if it doesn't have enough funds to pay for the transfer or if its balance drops below the minimum.
+// Accounts excluded due to failures are re-admitted after the configured period, the ones excluded due to
+// low balance are re-admitted once the balance is reported to be high enough again.
+type accountTracker struct {
+	maxConsecutiveFailures int
+	readmitAfter           time.Duration
+	minBalance             sdkmath.Int
+
+	mu       sync.Mutex
+	accounts map[string]*accountHealth
+}
+
+func newAccountTracker(
+	maxConsecutiveFailures int,
+	readmitAfter time.Duration,
+	minBalance sdkmath.Int,
+) *accountTracker {
+	return &accountTracker{
+		maxConsecutiveFailures: maxConsecutiveFailures,
+		readmitAfter:           readmitAfter,
+		minBalance:             minBalance,
+		accounts:               map[string]*accountHealth{},
+	}
+}
+
+func (t *accountTracker) account(address sdk.AccAddress) *accountHealth {
+	key := address.String()
+	health, ok := t.accounts[key]
+	if !ok {
+		health = &accountHealth{}
+		t.accounts[key] = health
+	}
+	return health
+}
+
+// isHealthy tells if batches might be dispatched to the account.
+func (t *accountTracker) isHealthy(address sdk.AccAddress) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health := t.account(address)
+	return !health.lowBalance && !time.Now().Before(health.excludedUntil)
+}
+
+// reportSuccess resets the failure counter of the account.
+func (t *accountTracker) reportSuccess(address sdk.AccAddress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.account(address).consecutiveFailures = 0
+}
+
+// reportFailure records the failure of the account and tells if the account has been excluded because of it.
+func (t *accountTracker) reportFailure(address sdk.AccAddress, err error) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health := t.account(address)
+	health.consecutiveFailures++
+	if health.consecutiveFailures < t.maxConsecutiveFailures && !isInsufficientFundsError(err) {
+		return false
+	}
+
+	health.consecutiveFailures = 0
+	health.excludedUntil = time.Now().Add(t.readmitAfter)
+	return true
+}
+
+// reportBalance excludes the account if its balance is below the minimum and re-admits it otherwise.
+func (t *accountTracker) reportBalance(address sdk.AccAddress, balance sdk.Coin) {
+	if t.minBalance.IsNil() || !t.minBalance.IsPositive() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.account(address).lowBalance = balance.Amount.LT(t.minBalance)
+}
+
+// forget drops the health of the account which is no longer used.
+func (t *accountTracker) forget(address sdk.AccAddress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.accounts, address.String())
+}
+
+func isInsufficientFundsError(err error) bool {
+	return errorMatches(err, cosmoserrors.ErrInsufficientFunds)
+}
+
+// errorMatches tells if err is the registered cosmos error. Errors returned by the simulation are not typed,
+// so in addition to errors.Is, the message is compared.
+func errorMatches(err error, target *sdkerrors.Error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, target) || strings.Contains(err.Error(), target.Error())
+}
@@ -0,0 +1,68 @@
+package coreum
+
+import (
+	"testing"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountTrackerConsecutiveFailures(t *testing.T) {
+	requireT := require.New(t)
+	address := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	tracker := newAccountTracker(2, time.Hour, sdkmath.ZeroInt())
+
+	requireT.False(tracker.reportFailure(address, errors.New("timeout")))
+	tracker.reportSuccess(address)
+	requireT.False(tracker.reportFailure(address, errors.New("timeout")))
+	requireT.True(tracker.isHealthy(address))
+
+	requireT.True(tracker.reportFailure(address, errors.New("timeout")))
+	requireT.False(tracker.isHealthy(address))
+}
+
+func TestAccountTrackerReadmit(t *testing.T) {
+	requireT := require.New(t)
+	address := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	tracker := newAccountTracker(1, 10*time.Millisecond, sdkmath.ZeroInt())
+
+	requireT.True(tracker.reportFailure(address, errors.New("timeout")))
+	requireT.False(tracker.isHealthy(address))
+	requireT.Eventually(func() bool {
+		return tracker.isHealthy(address)
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestAccountTrackerInsufficientFunds(t *testing.T) {
+	requireT := require.New(t)
+	address := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	tracker := newAccountTracker(3, time.Hour, sdkmath.ZeroInt())
+
+	// errors returned by the node lose their type, so only the message is available
+	err := errors.New("rpc error: code = Unknown desc = 5ucore is smaller than 10ucore: insufficient funds")
+	requireT.True(tracker.reportFailure(address, err))
+	requireT.False(tracker.isHealthy(address))
+
+	other := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	requireT.True(tracker.reportFailure(other, errors.Wrap(cosmoserrors.ErrInsufficientFunds, "too low")))
+}
+
+func TestAccountTrackerMinBalance(t *testing.T) {
+	requireT := require.New(t)
+	address := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+
+	tracker := newAccountTracker(3, time.Hour, sdkmath.NewInt(100))
+	tracker.reportBalance(address, sdk.NewInt64Coin("ucore", 99))
+	requireT.False(tracker.isHealthy(address))
+	tracker.reportBalance(address, sdk.NewInt64Coin("ucore", 100))
+	requireT.True(tracker.isHealthy(address))
+
+	disabled := newAccountTracker(3, time.Hour, sdkmath.ZeroInt())
+	disabled.reportBalance(address, sdk.NewInt64Coin("ucore", 0))
+	requireT.True(disabled.isHealthy(address))
+}
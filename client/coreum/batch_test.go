@@ -4,13 +4,18 @@ import (
 	"context"
 	"sync"
 	"testing"
+	"time"
 
 	sdkmath "cosmossdk.io/math"
 	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
 	"github.com/CoreumFoundation/coreum-tools/pkg/parallel"
@@ -19,6 +24,10 @@ import (
 type mockCoreumClient struct {
 	mu    sync.Mutex
 	calls []clientCall
+	// fail, if set, returns the error to be returned for the broadcast attempt.
+	fail func(fromAddress sdk.AccAddress, requests []transferRequest) error
+	// await, if set, is called when waiting for the tx to be committed.
+	await func(ctx context.Context, txHash string) error
 }
 
 type clientCall struct {
@@ -37,9 +46,31 @@ func (mc *mockCoreumClient) TransferToken(
 		fromAddress: fromAddress,
 		requests:    requests,
 	})
+	if mc.fail != nil {
+		if err := mc.fail(fromAddress, requests); err != nil {
+			return fromAddress.String(), err
+		}
+	}
 	return fromAddress.String(), nil
 }
 
+func (mc *mockCoreumClient) AwaitTx(ctx context.Context, txHash string) error {
+	if mc.await != nil {
+		return mc.await(ctx, txHash)
+	}
+	return nil
+}
+
+func (mc *mockCoreumClient) callCount() int {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return len(mc.calls)
+}
+
+func newAddress() sdk.AccAddress {
+	return sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+}
+
 func TestBatchSend(t *testing.T) {
 	assertT := assert.New(t)
 	requireT := require.New(t)
@@ -47,7 +78,10 @@ func TestBatchSend(t *testing.T) {
 	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
 	ctx, cancel := context.WithCancel(ctx)
 	t.Cleanup(cancel)
-	amount := sdk.NewCoin("test-denom", sdkmath.NewInt(13))
+	amount := sdk.NewCoins(
+		sdk.NewCoin("test-denom", sdkmath.NewInt(13)),
+		sdk.NewCoin("test-denom2", sdkmath.NewInt(7)),
+	)
 	fundingAddresses := []sdk.AccAddress{}
 	for range 2 {
 		address, err := sdk.AccAddressFromHexUnsafe(secp256k1.GenPrivKey().PubKey().Address().String())
@@ -56,7 +90,7 @@ func TestBatchSend(t *testing.T) {
 	}
 
 	mock := &mockCoreumClient{}
-	batcher := NewBatcher(mock, fundingAddresses, 10)
+	batcher := NewBatcher(mock, fundingAddresses, DefaultBatcherConfig())
 
 	group := parallel.NewGroup(ctx)
 	group.Spawn("batcher", parallel.Fail, batcher.Run)
@@ -70,9 +104,10 @@ func TestBatchSend(t *testing.T) {
 	wg.Add(requestCount)
 	for range requestCount {
 		go func() {
-			txHash, err := batcher.SendToken(ctx, nil, amount)
+			res, err := batcher.SendToken(ctx, newAddress(), amount)
 			if assert.NoError(t, err) {
-				assertT.Greater(len(txHash), 1)
+				assertT.Greater(len(res.TxHash), 1)
+				assertT.Equal(res.TxHash, res.FundingAddress.String())
 			}
 			wg.Done()
 		}()
@@ -86,7 +121,975 @@ func TestBatchSend(t *testing.T) {
 	totalAddressesCount := 0
 	for _, call := range mock.calls {
 		totalAddressesCount += len(call.requests)
+		for _, rq := range call.requests {
+			assertT.Equal(amount, rq.amount)
+		}
 	}
 
 	assertT.Equal(requestCount, totalAddressesCount)
 }
+
+func TestBatchSendAsyncProgress(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddress, err := sdk.AccAddressFromHexUnsafe(secp256k1.GenPrivKey().PubKey().Address().String())
+	requireT.NoError(err)
+
+	var mu sync.Mutex
+	var stages []Stage
+	config := DefaultBatcherConfig()
+	config.Progress = func(_ context.Context, stage Stage, res TransferResult, ids []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		stages = append(stages, stage)
+		// the same address is coalesced, so both requests are reported together
+		assert.ElementsMatch(t, []string{"first", "second"}, ids)
+		if stage == StageBroadcast {
+			assert.Equal(t, fundingAddress, res.FundingAddress)
+		}
+	}
+	batcher := NewBatcher(&mockCoreumClient{}, []sdk.AccAddress{fundingAddress}, config)
+
+	// requests are sent before the batcher is started to have them in the same batch
+	destAddress := newAddress()
+	doneCh := make(chan TransferResult, 2)
+	for _, id := range []string{"first", "second"} {
+		requireT.NoError(batcher.SendTokenAsync(
+			ctx,
+			destAddress,
+			sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))),
+			func() (string, error) {
+				return id, nil
+			},
+			func(res TransferResult, err error) {
+				assert.NoError(t, err)
+				doneCh <- res
+			},
+		))
+	}
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	for range 2 {
+		select {
+		case res := <-doneCh:
+			requireT.Equal(fundingAddress.String(), res.TxHash)
+		case <-ctx.Done():
+			requireT.FailNow("request not processed")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	requireT.Equal([]Stage{StageBatched, StageBroadcast}, stages)
+}
+
+func TestBatchSendRetriesOnAnotherAccount(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddresses := []sdk.AccAddress{}
+	for range 2 {
+		address, err := sdk.AccAddressFromHexUnsafe(secp256k1.GenPrivKey().PubKey().Address().String())
+		requireT.NoError(err)
+		fundingAddresses = append(fundingAddresses, address)
+	}
+	brokenAddress, healthyAddress := fundingAddresses[0], fundingAddresses[1]
+
+	var batcher *Batcher
+	mock := &mockCoreumClient{
+		fail: func(fromAddress sdk.AccAddress, _ []transferRequest) error {
+			if fromAddress.Equals(brokenAddress) {
+				// healthy account is re-admitted once the batch is taken by the broken one
+				batcher.ReportBalance(healthyAddress, sdk.NewInt64Coin("test-denom", 1000))
+				return errors.Wrap(cosmoserrors.ErrInsufficientFunds, "spendable balance is too low")
+			}
+			return nil
+		},
+	}
+	config := DefaultBatcherConfig()
+	config.MinBalance = sdkmath.NewInt(100)
+	batcher = NewBatcher(mock, fundingAddresses, config)
+	// healthy account is excluded until the first batch is taken by the broken one
+	batcher.ReportBalance(healthyAddress, sdk.NewInt64Coin("test-denom", 0))
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	resChan := make(chan result, 1)
+	requireT.NoError(batcher.SendTokenAsync(
+		ctx,
+		newAddress(),
+		sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))),
+		nil,
+		func(res TransferResult, err error) {
+			resChan <- result{res: res, err: err}
+		},
+	))
+	select {
+	case res := <-resChan:
+		requireT.NoError(res.err)
+		requireT.Equal(healthyAddress, res.res.FundingAddress)
+	case <-ctx.Done():
+		requireT.FailNow("request not processed")
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	requireT.Len(mock.calls, 2)
+	requireT.Equal(brokenAddress, mock.calls[0].fromAddress)
+}
+
+func TestBatchSendFailsWhenAllAccountsFail(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddress, err := sdk.AccAddressFromHexUnsafe(secp256k1.GenPrivKey().PubKey().Address().String())
+	requireT.NoError(err)
+
+	mock := &mockCoreumClient{
+		fail: func(sdk.AccAddress, []transferRequest) error {
+			return errors.Wrap(cosmoserrors.ErrInsufficientFunds, "spendable balance is too low")
+		},
+	}
+	batcher := NewBatcher(mock, []sdk.AccAddress{fundingAddress}, DefaultBatcherConfig())
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	_, err = batcher.SendToken(ctx, newAddress(), sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))))
+	requireT.ErrorIs(err, cosmoserrors.ErrInsufficientFunds)
+}
+
+func TestBatchSendFailsWhenAllAccountsExcluded(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddress := newAddress()
+
+	mock := &mockCoreumClient{}
+	config := DefaultBatcherConfig()
+	config.MinBalance = sdkmath.NewInt(100)
+	batcher := NewBatcher(mock, []sdk.AccAddress{fundingAddress}, config)
+	batcher.ReportBalance(fundingAddress, sdk.NewInt64Coin("test-denom", 0))
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	// request fails immediately instead of waiting until the account is re-admitted
+	sendCtx, sendCancel := context.WithTimeout(ctx, time.Second)
+	defer sendCancel()
+	_, err := batcher.SendToken(sendCtx, newAddress(), sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))))
+	requireT.ErrorIs(err, ErrNoFundingAccount)
+	requireT.Zero(mock.callCount())
+}
+
+func TestBatchSendRetriesTransientErrors(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddress, err := sdk.AccAddressFromHexUnsafe(secp256k1.GenPrivKey().PubKey().Address().String())
+	requireT.NoError(err)
+
+	attempts := 0
+	mock := &mockCoreumClient{
+		fail: func(sdk.AccAddress, []transferRequest) error {
+			attempts++
+			if attempts <= 2 {
+				return errors.Wrap(cosmoserrors.ErrWrongSequence, "account sequence mismatch")
+			}
+			return nil
+		},
+	}
+	config := DefaultBatcherConfig()
+	config.RetryBackoff = time.Millisecond
+	batcher := NewBatcher(mock, []sdk.AccAddress{fundingAddress}, config)
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	res, err := batcher.SendToken(ctx, newAddress(), sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))))
+	requireT.NoError(err)
+	requireT.Equal(fundingAddress, res.FundingAddress)
+	requireT.Equal(3, attempts)
+}
+
+func TestBatchSendAmbiguousErrorStaysOnAccount(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddresses := []sdk.AccAddress{newAddress(), newAddress()}
+
+	attempts := 0
+	mock := &mockCoreumClient{
+		fail: func(sdk.AccAddress, []transferRequest) error {
+			attempts++
+			if attempts == 1 {
+				return status.Error(codes.Unavailable, "connection reset")
+			}
+			// tx broadcast on the first attempt might have been accepted
+			return errors.Wrap(cosmoserrors.ErrWrongSequence, "account sequence mismatch")
+		},
+	}
+	config := DefaultBatcherConfig()
+	config.RetryBackoff = time.Millisecond
+	batcher := NewBatcher(mock, fundingAddresses, config)
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	res, err := batcher.SendToken(ctx, newAddress(), sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))))
+	requireT.ErrorIs(err, ErrOutcomeUnknown)
+	requireT.NotEmpty(res.TxHash)
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	requireT.Len(mock.calls, 2)
+	requireT.Equal(mock.calls[0].fromAddress, mock.calls[1].fromAddress)
+}
+
+func TestBatchSendDoesNotBisectAccountErrors(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddress := newAddress()
+
+	mock := &mockCoreumClient{
+		fail: func(sdk.AccAddress, []transferRequest) error {
+			return errors.Wrap(cosmoserrors.ErrInsufficientFee, "insufficient fees")
+		},
+	}
+	batcher := NewBatcher(mock, []sdk.AccAddress{fundingAddress}, DefaultBatcherConfig())
+
+	// requests are sent before the batcher is started to have them all in the same batch
+	resChan := make(chan result, 4)
+	for range cap(resChan) {
+		requireT.NoError(batcher.SendTokenAsync(
+			ctx,
+			newAddress(),
+			sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))),
+			nil,
+			func(res TransferResult, err error) {
+				resChan <- result{res: res, err: err}
+			},
+		))
+	}
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	for range cap(resChan) {
+		select {
+		case res := <-resChan:
+			requireT.ErrorIs(res.err, cosmoserrors.ErrInsufficientFee)
+		case <-ctx.Done():
+			requireT.FailNow("request not processed")
+		}
+	}
+	requireT.Equal(1, mock.callCount())
+}
+
+func TestBatchSendAwaitFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		awaitErr    error
+		expectedErr error
+	}{
+		{
+			name:        "executed with error",
+			awaitErr:    errors.Wrap(cosmoserrors.ErrInsufficientFunds, "transaction failed"),
+			expectedErr: cosmoserrors.ErrInsufficientFunds,
+		},
+		{
+			name:        "not found",
+			awaitErr:    status.Error(codes.NotFound, "tx not found"),
+			expectedErr: ErrOutcomeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireT := require.New(t)
+
+			ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+			ctx, cancel := context.WithCancel(ctx)
+			t.Cleanup(cancel)
+
+			mock := &mockCoreumClient{
+				await: func(context.Context, string) error {
+					return tt.awaitErr
+				},
+			}
+			batcher := NewBatcher(mock, []sdk.AccAddress{newAddress()}, DefaultBatcherConfig())
+
+			group := parallel.NewGroup(ctx)
+			group.Spawn("batcher", parallel.Fail, batcher.Run)
+			t.Cleanup(func() {
+				group.Exit(nil)
+				_ = group.Wait()
+			})
+
+			_, err := batcher.SendToken(ctx, newAddress(), sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))))
+			requireT.ErrorIs(err, tt.expectedErr)
+		})
+	}
+}
+
+func TestBatchSendBisectsRejectedBatch(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddress, err := sdk.AccAddressFromHexUnsafe(secp256k1.GenPrivKey().PubKey().Address().String())
+	requireT.NoError(err)
+
+	destAddresses := []sdk.AccAddress{}
+	for range 7 {
+		destAddresses = append(destAddresses, sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()))
+	}
+	blockedAddress := destAddresses[4]
+
+	mock := &mockCoreumClient{
+		fail: func(_ sdk.AccAddress, requests []transferRequest) error {
+			for _, rq := range requests {
+				if rq.destAddress.Equals(blockedAddress) {
+					return errors.Wrapf(cosmoserrors.ErrUnauthorized, "%s is not allowed to receive funds", blockedAddress)
+				}
+			}
+			return nil
+		},
+	}
+	batcher := NewBatcher(mock, []sdk.AccAddress{fundingAddress}, DefaultBatcherConfig())
+
+	// requests are sent before the batcher is started to have them all in the same batch
+	resultsByAddress := map[string]chan result{}
+	for _, destAddress := range destAddresses {
+		resChan := make(chan result, 1)
+		resultsByAddress[destAddress.String()] = resChan
+		requireT.NoError(batcher.SendTokenAsync(
+			ctx,
+			destAddress,
+			sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))),
+			nil,
+			func(res TransferResult, err error) {
+				resChan <- result{res: res, err: err}
+			},
+		))
+	}
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	for _, destAddress := range destAddresses {
+		select {
+		case res := <-resultsByAddress[destAddress.String()]:
+			if destAddress.Equals(blockedAddress) {
+				requireT.ErrorIs(res.err, cosmoserrors.ErrUnauthorized)
+			} else {
+				requireT.NoError(res.err)
+			}
+		case <-ctx.Done():
+			requireT.FailNow("request not processed")
+		}
+	}
+	requireT.True(batcher.accounts.isHealthy(fundingAddress))
+}
+
+func TestBatchSendPipelinesTxs(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddress, err := sdk.AccAddressFromHexUnsafe(secp256k1.GenPrivKey().PubKey().Address().String())
+	requireT.NoError(err)
+
+	release := make(chan struct{})
+	mock := &mockCoreumClient{
+		await: func(context.Context, string) error {
+			<-release
+			return nil
+		},
+	}
+	config := DefaultBatcherConfig()
+	config.MaxPendingTxs = 2
+	batcher := NewBatcher(mock, []sdk.AccAddress{fundingAddress}, config)
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+	releaseOnce := sync.OnceFunc(func() { close(release) })
+	t.Cleanup(releaseOnce)
+
+	resChan := make(chan result, 3)
+	send := func() {
+		requireT.NoError(batcher.SendTokenAsync(
+			ctx,
+			newAddress(),
+			sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))),
+			nil,
+			func(res TransferResult, err error) {
+				resChan <- result{res: res, err: err}
+			},
+		))
+	}
+
+	// two txs are broadcast before the first one is committed
+	send()
+	requireT.Eventually(func() bool { return mock.callCount() == 1 }, time.Second, 5*time.Millisecond)
+	send()
+	requireT.Eventually(func() bool { return mock.callCount() == 2 }, time.Second, 5*time.Millisecond)
+
+	// third one waits until the pending one is committed
+	send()
+	time.Sleep(50 * time.Millisecond)
+	requireT.Equal(2, mock.callCount())
+
+	releaseOnce()
+	for range 3 {
+		select {
+		case res := <-resChan:
+			requireT.NoError(res.err)
+		case <-ctx.Done():
+			requireT.FailNow("request not processed")
+		}
+	}
+	requireT.Equal(3, mock.callCount())
+}
+
+func TestBatchSendAddRetireFundingAccount(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	first, second := newAddress(), newAddress()
+
+	release := make(chan struct{})
+	mock := &mockCoreumClient{
+		await: func(_ context.Context, txHash string) error {
+			if txHash == first.String() {
+				<-release
+			}
+			return nil
+		},
+	}
+	batcher := NewBatcher(mock, []sdk.AccAddress{first}, DefaultBatcherConfig())
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+	releaseOnce := sync.OnceFunc(func() { close(release) })
+	t.Cleanup(releaseOnce)
+
+	resChan := make(chan result, 2)
+	send := func() {
+		requireT.NoError(batcher.SendTokenAsync(
+			ctx,
+			newAddress(),
+			sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))),
+			nil,
+			func(res TransferResult, err error) {
+				resChan <- result{res: res, err: err}
+			},
+		))
+	}
+
+	send()
+	requireT.Eventually(func() bool { return mock.callCount() == 1 }, time.Second, 5*time.Millisecond)
+
+	requireT.NoError(batcher.AddFundingAccount(second))
+	requireT.Error(batcher.AddFundingAccount(second))
+	done, err := batcher.RetireFundingAccount(first)
+	requireT.NoError(err)
+	requireT.Equal([]sdk.AccAddress{second}, batcher.FundingAddresses())
+	_, err = batcher.RetireFundingAccount(second)
+	requireT.Error(err)
+
+	// new batch is sent from the added account, while the retired one still waits for its pending tx
+	send()
+	select {
+	case res := <-resChan:
+		requireT.NoError(res.err)
+		requireT.Equal(second, res.res.FundingAddress)
+	case <-ctx.Done():
+		requireT.FailNow("request not processed")
+	}
+	select {
+	case <-done:
+		requireT.FailNow("retired account finished before its pending tx was committed")
+	default:
+	}
+
+	releaseOnce()
+	select {
+	case res := <-resChan:
+		requireT.NoError(res.err)
+		requireT.Equal(first, res.res.FundingAddress)
+	case <-ctx.Done():
+		requireT.FailNow("request not processed")
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		requireT.FailNow("retired account not finished")
+	}
+
+	// state of the account added back is kept
+	requireT.False(batcher.ForgetFundingAccount(second))
+	batcher.accounts.reportFailure(first, cosmoserrors.ErrInsufficientFunds)
+	requireT.True(batcher.ForgetFundingAccount(first))
+	requireT.NotContains(batcher.accounts.accounts, first.String())
+	// balance probed after the account was retired doesn't bring its state back
+	batcher.ReportBalance(first, sdk.NewInt64Coin("test-denom", 0))
+	requireT.NotContains(batcher.accounts.accounts, first.String())
+}
+
+func TestBatchSendLinger(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddress, err := sdk.AccAddressFromHexUnsafe(secp256k1.GenPrivKey().PubKey().Address().String())
+	requireT.NoError(err)
+
+	mock := &mockCoreumClient{}
+	config := DefaultBatcherConfig()
+	config.Linger = 500 * time.Millisecond
+	batcher := NewBatcher(mock, []sdk.AccAddress{fundingAddress}, config)
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	// requests arriving within the linger period are sent together
+	resChan := make(chan result, 3)
+	for range 3 {
+		requireT.NoError(batcher.SendTokenAsync(
+			ctx,
+			newAddress(),
+			sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))),
+			nil,
+			func(res TransferResult, err error) {
+				resChan <- result{res: res, err: err}
+			},
+		))
+		time.Sleep(20 * time.Millisecond)
+	}
+	for range 3 {
+		select {
+		case res := <-resChan:
+			requireT.NoError(res.err)
+		case <-ctx.Done():
+			requireT.FailNow("request not processed")
+		}
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	requireT.Len(mock.calls, 1)
+	requireT.Len(mock.calls[0].requests, 3)
+}
+
+func TestBatchSendMaxGas(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	fundingAddress, err := sdk.AccAddressFromHexUnsafe(secp256k1.GenPrivKey().PubKey().Address().String())
+	requireT.NoError(err)
+
+	amount := sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13)))
+	mock := &mockCoreumClient{}
+	config := DefaultBatcherConfig()
+	maxGas, err := config.GasEstimator.Estimate(newMultiSendMsg(fundingAddress, []transferRequest{
+		{amount: amount}, {amount: amount},
+	}))
+	requireT.NoError(err)
+	config.MaxGas = maxGas
+	batcher := NewBatcher(mock, []sdk.AccAddress{fundingAddress}, config)
+
+	// requests are sent before the batcher is started to have them all available at once
+	resChan := make(chan result, 5)
+	for range 5 {
+		requireT.NoError(batcher.SendTokenAsync(
+			ctx,
+			newAddress(),
+			amount,
+			nil,
+			func(res TransferResult, err error) {
+				resChan <- result{res: res, err: err}
+			},
+		))
+	}
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	for range 5 {
+		select {
+		case res := <-resChan:
+			requireT.NoError(res.err)
+		case <-ctx.Done():
+			requireT.FailNow("request not processed")
+		}
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	requireT.Len(mock.calls, 3)
+	for _, call := range mock.calls {
+		requireT.LessOrEqual(len(call.requests), 2)
+	}
+}
+
+func TestBatchSendMaxGasExceededByRequest(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+
+	mock := &mockCoreumClient{}
+	config := DefaultBatcherConfig()
+	config.MaxGas = 1
+	batcher := NewBatcher(mock, []sdk.AccAddress{newAddress()}, config)
+
+	group := parallel.NewGroup(ctx)
+	group.Spawn("batcher", parallel.Fail, batcher.Run)
+	t.Cleanup(func() {
+		group.Exit(nil)
+		_ = group.Wait()
+	})
+
+	_, err := batcher.SendToken(ctx, newAddress(), sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13))))
+	requireT.ErrorIs(err, ErrGasLimitExceeded)
+	requireT.Zero(mock.callCount())
+}
+
+func TestBatchSendDuplicates(t *testing.T) {
+	amount := sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13)))
+	otherAmount := sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(7)))
+
+	tests := []struct {
+		name          string
+		policy        DuplicatePolicy
+		amounts       []sdk.Coins
+		expectedCalls int
+		expectedErrs  []error
+	}{
+		{
+			name:          "allow",
+			policy:        DuplicatesAllow,
+			amounts:       []sdk.Coins{amount, amount},
+			expectedCalls: 1,
+			expectedErrs:  []error{nil, nil},
+		},
+		{
+			name:          "coalesce",
+			policy:        DuplicatesCoalesce,
+			amounts:       []sdk.Coins{amount, amount, amount},
+			expectedCalls: 1,
+			expectedErrs:  []error{nil, nil, nil},
+		},
+		{
+			name:          "coalesce_different_amounts",
+			policy:        DuplicatesCoalesce,
+			amounts:       []sdk.Coins{amount, otherAmount},
+			expectedCalls: 2,
+			expectedErrs:  []error{nil, nil},
+		},
+		{
+			name:          "reject",
+			policy:        DuplicatesReject,
+			amounts:       []sdk.Coins{amount, amount},
+			expectedCalls: 1,
+			expectedErrs:  []error{nil, ErrDuplicateRequest},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireT := require.New(t)
+
+			ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+			ctx, cancel := context.WithCancel(ctx)
+			t.Cleanup(cancel)
+			fundingAddress := newAddress()
+			destAddress := newAddress()
+
+			mock := &mockCoreumClient{}
+			config := DefaultBatcherConfig()
+			config.Duplicates = tt.policy
+			batcher := NewBatcher(mock, []sdk.AccAddress{fundingAddress}, config)
+
+			// requests are sent before the batcher is started to have them all in the same batch
+			resChans := make([]chan result, 0, len(tt.amounts))
+			for _, amount := range tt.amounts {
+				resChan := make(chan result, 1)
+				resChans = append(resChans, resChan)
+				requireT.NoError(batcher.SendTokenAsync(
+					ctx,
+					destAddress,
+					amount,
+					nil,
+					func(res TransferResult, err error) {
+						resChan <- result{res: res, err: err}
+					},
+				))
+			}
+
+			group := parallel.NewGroup(ctx)
+			group.Spawn("batcher", parallel.Fail, batcher.Run)
+			t.Cleanup(func() {
+				group.Exit(nil)
+				_ = group.Wait()
+			})
+
+			for i, resChan := range resChans {
+				select {
+				case res := <-resChan:
+					if tt.expectedErrs[i] != nil {
+						requireT.ErrorIs(res.err, tt.expectedErrs[i])
+						continue
+					}
+					requireT.NoError(res.err)
+					requireT.Equal(fundingAddress.String(), res.res.TxHash)
+				case <-ctx.Done():
+					requireT.FailNow("request not processed")
+				}
+			}
+
+			mock.mu.Lock()
+			defer mock.mu.Unlock()
+			requireT.Len(mock.calls, tt.expectedCalls)
+			outputs := 0
+			for _, call := range mock.calls {
+				outputs += len(call.requests)
+			}
+			if tt.policy == DuplicatesAllow {
+				requireT.Equal(len(tt.amounts), outputs)
+			} else {
+				requireT.Equal(tt.expectedCalls, outputs)
+			}
+		})
+	}
+}
+
+func TestBatchSendQueueFull(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	amount := sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13)))
+	config := DefaultBatcherConfig()
+	config.MaxQueueDepth = 2
+	// batcher is not started, so the queue is never drained
+	batcher := NewBatcher(&mockCoreumClient{}, []sdk.AccAddress{newAddress()}, config)
+
+	done := func(TransferResult, error) {}
+	registered := 0
+	register := func() (string, error) {
+		registered++
+		return "", nil
+	}
+	for range 2 {
+		requireT.NoError(batcher.SendTokenAsync(ctx, newAddress(), amount, register, done))
+	}
+	// request rejected because of the full queue is not registered
+	requireT.ErrorIs(batcher.SendTokenAsync(ctx, newAddress(), amount, register, done), ErrQueueFull)
+	requireT.Equal(2, registered)
+
+	// request waiting for the space in the queue gives up once its context is canceled
+	batcher.enqueueTimeout = time.Minute
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	requireT.ErrorIs(batcher.SendTokenAsync(cancelledCtx, newAddress(), amount, register, done), context.Canceled)
+	requireT.Equal(2, registered)
+
+	// request waiting for the space in the queue doesn't delay the shutdown and fails once it is started
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- batcher.SendTokenAsync(ctx, newAddress(), amount, register, done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	closed := make(chan struct{})
+	go func() {
+		batcher.close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		requireT.FailNow("shutdown waits for the request blocked on the full queue")
+	}
+	select {
+	case err := <-errChan:
+		requireT.ErrorIs(err, ErrShuttingDown)
+	case <-time.After(time.Second):
+		requireT.FailNow("request blocked on the full queue not rejected")
+	}
+	requireT.ErrorIs(batcher.SendTokenAsync(ctx, newAddress(), amount, register, done), ErrShuttingDown)
+	requireT.Equal(2, registered)
+}
+
+func TestBatchSendRegisterFailureReleasesSlot(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	amount := sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13)))
+	config := DefaultBatcherConfig()
+	config.MaxQueueDepth = 1
+	// batcher is not started, so the queue is never drained
+	batcher := NewBatcher(&mockCoreumClient{}, []sdk.AccAddress{newAddress()}, config)
+
+	done := func(TransferResult, error) {}
+	registerErr := errors.New("ledger unavailable")
+	requireT.ErrorIs(batcher.SendTokenAsync(ctx, newAddress(), amount, func() (string, error) {
+		return "", registerErr
+	}, done), registerErr)
+	requireT.NoError(batcher.SendTokenAsync(ctx, newAddress(), amount, nil, done))
+}
+
+func TestBatchSendDrain(t *testing.T) {
+	tests := []struct {
+		name        string
+		commit      bool
+		expectedErr error
+	}{
+		{
+			name:   "committed_before_deadline",
+			commit: true,
+		},
+		{
+			name:        "deadline_expired",
+			expectedErr: ErrOutcomeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireT := require.New(t)
+
+			ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+			amount := sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(13)))
+
+			commit := make(chan struct{})
+			mock := &mockCoreumClient{
+				await: func(ctx context.Context, _ string) error {
+					select {
+					case <-commit:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				},
+			}
+			config := DefaultBatcherConfig()
+			config.DrainTimeout = 200 * time.Millisecond
+			batcher := NewBatcher(mock, []sdk.AccAddress{newAddress()}, config)
+
+			runCtx, cancel := context.WithCancel(ctx)
+			t.Cleanup(cancel)
+			runErr := make(chan error, 1)
+			go func() {
+				runErr <- batcher.Run(runCtx)
+			}()
+
+			resChan := make(chan result, 1)
+			requireT.NoError(batcher.SendTokenAsync(ctx, newAddress(), amount, nil, func(res TransferResult, err error) {
+				resChan <- result{res: res, err: err}
+			}))
+			requireT.Eventually(func() bool { return mock.callCount() == 1 }, time.Second, 5*time.Millisecond)
+
+			// requests are rejected once shutdown is started, while the accepted one is still processed
+			cancel()
+			requireT.Eventually(func() bool {
+				return errors.Is(batcher.SendTokenAsync(ctx, newAddress(), amount, nil, nil), ErrShuttingDown)
+			}, time.Second, 5*time.Millisecond)
+			if tt.commit {
+				close(commit)
+			}
+
+			select {
+			case res := <-resChan:
+				if tt.expectedErr != nil {
+					requireT.ErrorIs(res.err, tt.expectedErr)
+				} else {
+					requireT.NoError(res.err)
+				}
+				requireT.NotEmpty(res.res.TxHash)
+			case <-time.After(time.Second):
+				requireT.FailNow("request not completed")
+			}
+
+			select {
+			case <-runErr:
+			case <-time.After(time.Second):
+				requireT.FailNow("batcher not stopped after drain")
+			}
+		})
+	}
+}
@@ -2,42 +2,227 @@ package coreum
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"time"
 
+	sdkmath "cosmossdk.io/math"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
 
 	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
 	"github.com/CoreumFoundation/coreum-tools/pkg/parallel"
 )
 
+// closedTimeChan is used as the timeout which has already expired.
+var closedTimeChan = func() <-chan time.Time {
+	ch := make(chan time.Time)
+	close(ch)
+	return ch
+}()
+
+const (
+	// accountHealthCheckInterval is how often the excluded funding account checks if it might be used again.
+	accountHealthCheckInterval = time.Second
+	// batchRequestTimeout is the timeout of a single request sent to the chain while processing the batch.
+	batchRequestTimeout = 20 * time.Second
+)
+
+// Errors returned by the Batcher.
+var (
+	// ErrDuplicateRequest is returned if the request for the same destination address is already in the batch.
+	ErrDuplicateRequest = errors.New("request for the same address is already being processed")
+	// ErrQueueFull is returned if the request can't be enqueued because too many requests wait to be processed.
+	ErrQueueFull = errors.New("request queue is full")
+	// ErrShuttingDown is returned if the request is received or not sent yet when the Batcher is shutting down.
+	ErrShuttingDown = errors.New("faucet is shutting down")
+	// ErrOutcomeUnknown is returned if it is unknown whether the node accepted the tx or the drain deadline expires
+	// before it is known whether the tx was committed.
+	ErrOutcomeUnknown = errors.New("outcome of the transfer is unknown")
+	// ErrNoFundingAccount is returned if all the funding accounts are excluded, so the request can't be sent.
+	ErrNoFundingAccount = errors.New("no funding account is available")
+	// ErrGasLimitExceeded is returned if the request alone exceeds the maximum gas of the batch.
+	ErrGasLimitExceeded = errors.New("request exceeds the maximum gas of the batch")
+)
+
+// DuplicatePolicy defines how the requests for the same destination address in the same batch are handled.
+type DuplicatePolicy string
+
+// Supported duplicate policies.
+const (
+	// DuplicatesAllow sends each of the requests as a separate output.
+	DuplicatesAllow DuplicatePolicy = "allow"
+	// DuplicatesCoalesce sends the requested amount once, all the callers get the same result. Requests asking
+	// for different amounts are sent in separate batches.
+	DuplicatesCoalesce DuplicatePolicy = "coalesce"
+	// DuplicatesReject rejects the subsequent requests with ErrDuplicateRequest.
+	DuplicatesReject DuplicatePolicy = "reject"
+)
+
+// Validate checks if the policy is supported.
+func (p DuplicatePolicy) Validate() error {
+	switch p {
+	case DuplicatesAllow, DuplicatesCoalesce, DuplicatesReject:
+		return nil
+	default:
+		return errors.Errorf("unsupported duplicate policy %q", p)
+	}
+}
+
+// BatcherConfig is the configuration of the Batcher.
+type BatcherConfig struct {
+	// BatchSize is the maximum number of requests sent in a single transaction.
+	BatchSize int
+	// MaxQueueDepth is the maximum number of requests of each priority waiting to be batched.
+	MaxQueueDepth int
+	// LaneWeights defines how the requests of different priorities are drained from the queue.
+	LaneWeights LaneWeights
+	// EnqueueTimeout is how long the request waits for the space in the full queue before ErrQueueFull is returned.
+	// Zero rejects the request immediately.
+	EnqueueTimeout time.Duration
+	// Linger is how long the batch waits for more requests after the first one is received.
+	// Zero seals the batch as soon as there are no more requests waiting.
+	Linger time.Duration
+	// MaxGas is the maximum gas of the transaction sending the batch. Zero disables the limit.
+	MaxGas uint64
+	// GasEstimator computes the gas of the transaction sending the batch, to check it against MaxGas.
+	GasEstimator GasEstimator
+	// Duplicates defines how the requests for the same destination address in the same batch are handled.
+	Duplicates DuplicatePolicy
+	// MaxConsecutiveFailures is the number of failures in a row after which the funding account is excluded.
+	MaxConsecutiveFailures int
+	// ReadmitAfter is the period after which the funding account excluded due to failures is used again.
+	ReadmitAfter time.Duration
+	// MinBalance is the balance of the network denom below which the funding account is excluded.
+	// Zero disables the check.
+	MinBalance sdkmath.Int
+	// MaxRetries is the number of times the batch is broadcast again after a transient failure.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, it is doubled on each subsequent one.
+	RetryBackoff time.Duration
+	// MaxPendingTxs is the number of txs broadcast from the funding account which might wait to be committed.
+	MaxPendingTxs int
+	// DrainTimeout is how long the requests already accepted are processed after shutdown is started. Batches not
+	// broadcast by then fail with ErrShuttingDown, the ones not confirmed yet complete with ErrOutcomeUnknown.
+	DrainTimeout time.Duration
+	// Progress, if not nil, is called whenever processing of the batch reaches the next stage.
+	Progress ProgressFunc
+}
+
+// DefaultBatcherConfig returns the default configuration of the Batcher.
+func DefaultBatcherConfig() BatcherConfig {
+	return BatcherConfig{
+		BatchSize:              10,
+		MaxQueueDepth:          1000,
+		LaneWeights:            DefaultLaneWeights(),
+		Linger:                 100 * time.Millisecond,
+		GasEstimator:           DefaultGasEstimator(),
+		Duplicates:             DuplicatesCoalesce,
+		MaxConsecutiveFailures: 3,
+		ReadmitAfter:           time.Minute,
+		MinBalance:             sdkmath.ZeroInt(),
+		MaxRetries:             3,
+		RetryBackoff:           500 * time.Millisecond,
+		MaxPendingTxs:          3,
+		DrainTimeout:           30 * time.Second,
+	}
+}
+
 // Batcher exposes functionality to batch many transfer requests.
 type Batcher struct {
-	requestBuffer    chan request
+	lanes            *lanes
 	client           coreumClient
 	fundingAddresses []sdk.AccAddress
 	batchSize        int
-	batchChan        chan batch
+	linger           time.Duration
+	maxGas           uint64
+	gasEstimator     GasEstimator
+	duplicates       DuplicatePolicy
+	enqueueTimeout   time.Duration
+	batchSizes       prometheus.Histogram
+	queueRejected    *prometheus.CounterVec
+	batchChan        chan *batch
+	accounts         *accountTracker
+	maxRetries       int
+	retryBackoff     time.Duration
+	maxPendingTxs    int
+	drainTimeout     time.Duration
+	progress         ProgressFunc
+	// drainCtx is used to process the accepted requests, it is cancelled once the drain deadline expires.
+	drainCtx  context.Context
+	stopDrain context.CancelFunc
 
 	mu      sync.RWMutex
 	stopped bool
+	// closing is closed once the batcher stops accepting requests, to wake up the requests waiting for the room
+	// in the queue.
+	closing chan struct{}
+
+	// workersMu guards the funding addresses and their workers, which are added and retired while running.
+	workersMu sync.Mutex
+	// workersCtx is the context the workers are started with, nil until Run is called.
+	workersCtx    context.Context
+	workersClosed bool
+	workers       map[string]*worker
+	workersWG     sync.WaitGroup
+}
+
+// worker sends the batches from a single funding account.
+type worker struct {
+	// retry receives the batches failed on other funding accounts.
+	retry chan *batch
+	// stopped is set, under workersMu, once the worker doesn't take the batches for retry anymore.
+	stopped bool
+	// retire is closed to stop taking new batches.
+	retire chan struct{}
+	// done is closed once the batches taken by the worker are processed.
+	done chan struct{}
 }
 
 // NewBatcher returns new instance of Batcher type.
 func NewBatcher(
 	client coreumClient,
 	fundingAddresses []sdk.AccAddress,
-	batchSize int,
+	config BatcherConfig,
 ) *Batcher {
-	requestBufferSize := batchSize // number of requests that will be buffered to be batched
+	drainCtx, stopDrain := context.WithCancel(context.Background())
 	b := &Batcher{
-		requestBuffer:    make(chan request, requestBufferSize),
+		lanes:            newLanes(config.MaxQueueDepth, config.LaneWeights),
 		client:           client,
-		fundingAddresses: fundingAddresses,
-		batchSize:        batchSize,
-		batchChan:        make(chan batch),
-		mu:               sync.RWMutex{},
+		fundingAddresses: append([]sdk.AccAddress{}, fundingAddresses...),
+		batchSize:        config.BatchSize,
+		linger:           config.Linger,
+		maxGas:           config.MaxGas,
+		gasEstimator:     config.GasEstimator,
+		duplicates:       config.Duplicates,
+		enqueueTimeout:   config.EnqueueTimeout,
+		batchChan:        make(chan *batch),
+		accounts: newAccountTracker(
+			config.MaxConsecutiveFailures,
+			config.ReadmitAfter,
+			config.MinBalance,
+		),
+		batchSizes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "batch_size",
+			Help:    "Number of transfer requests sent in a single transaction",
+			Buckets: []float64{1, 2, 5, 10, 20, 50, 100},
+		}),
+		queueRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "queue_rejected_total",
+			Help: "Number of requests rejected because the request queue was full",
+		}, []string{"lane"}),
+		maxRetries:    config.MaxRetries,
+		retryBackoff:  config.RetryBackoff,
+		maxPendingTxs: config.MaxPendingTxs,
+		drainTimeout:  config.DrainTimeout,
+		progress:      config.Progress,
+		drainCtx:      drainCtx,
+		stopDrain:     stopDrain,
+		mu:            sync.RWMutex{},
+		closing:       make(chan struct{}),
+		workers:       map[string]*worker{},
 	}
 
 	return b
@@ -50,145 +235,829 @@ type coreumClient interface {
 		fromAddress sdk.AccAddress,
 		requests ...transferRequest,
 	) (string, error)
+	AwaitTx(ctx context.Context, txHash string) error
 }
 
+// TransferResult is the result of the transfer executed by the Batcher.
+type TransferResult struct {
+	TxHash         string
+	FundingAddress sdk.AccAddress
+}
+
+// Stage is the stage of processing the transfer request.
+type Stage string
+
+// Stages reported by the Batcher before the transfer request is processed.
+const (
+	StageBatched   Stage = "batched"
+	StageBroadcast Stage = "broadcast"
+)
+
+// ProgressFunc is called when processing of the batch reaches the next stage. It receives the IDs the requests
+// of the batch were sent with, so the progress of the whole batch might be stored at once.
+type ProgressFunc func(ctx context.Context, stage Stage, res TransferResult, ids []string)
+
+// DoneFunc is called once the transfer request is processed.
+type DoneFunc func(res TransferResult, err error)
+
+// RegisterFunc is called once there is room in the queue for the request, before it is enqueued. It returns the ID
+// of the request, passed to the progress function of the Batcher. Request is not enqueued if it fails.
+type RegisterFunc func() (string, error)
+
 type result struct {
-	txHash string
-	err    error
+	res TransferResult
+	err error
 }
 
 type request struct {
-	responseChan chan result
-	req          transferRequest
+	id   string
+	done DoneFunc
+	req  transferRequest
+	// coalesced are the requests for the same address and amount which are fulfilled by this one.
+	coalesced []request
+}
+
+// ids returns the IDs of the request and the ones coalesced with it.
+func (r request) ids() []string {
+	var ids []string
+	if r.id != "" {
+		ids = append(ids, r.id)
+	}
+	for _, c := range r.coalesced {
+		ids = append(ids, c.ids()...)
+	}
+	return ids
+}
+
+func (r request) complete(res TransferResult, err error) {
+	r.done(res, err)
+	for _, c := range r.coalesced {
+		c.complete(res, err)
+	}
 }
 
 // SendToken receives a single transfer token request, batch sends them and returns the result.
-func (b *Batcher) SendToken(ctx context.Context, destAddress sdk.AccAddress, amount sdk.Coin) (string, error) {
-	resChan, err := b.requestFund(destAddress, amount)
+func (b *Batcher) SendToken(ctx context.Context, destAddress sdk.AccAddress, amount sdk.Coins) (TransferResult, error) {
+	resChan := make(chan result, 1)
+	err := b.SendTokenAsync(ctx, destAddress, amount, nil, func(res TransferResult, err error) {
+		resChan <- result{res: res, err: err}
+	})
 	if err != nil {
-		return "", err
+		return TransferResult{}, err
 	}
 	select {
 	case res := <-resChan:
-		return res.txHash, res.err
+		return res.res, res.err
 	case d := <-ctx.Done():
-		return "", errors.Errorf("request aborted, %v", d)
+		return TransferResult{}, errors.Errorf("request aborted, %v", d)
+	}
+}
+
+// SendTokenAsync receives a single transfer token request and returns once it is enqueued. ErrQueueFull is returned
+// if the queue is full. Register function, if not nil, is called once there is room for the request in the queue,
+// so the request rejected because of the full queue is not registered. Done function is called once the batch
+// containing the request is processed, even if the caller is no longer interested in the result.
+func (b *Batcher) SendTokenAsync(
+	ctx context.Context,
+	destAddress sdk.AccAddress,
+	amount sdk.Coins,
+	register RegisterFunc,
+	done DoneFunc,
+) error {
+	return b.requestFund(ctx, destAddress, amount, register, done)
+}
+
+// ReportBalance is called when the balance of the funding account is probed. Funding account with the balance
+// below the configured minimum is excluded until its balance is reported to be high enough.
+func (b *Batcher) ReportBalance(address sdk.AccAddress, balance sdk.Coin) {
+	b.workersMu.Lock()
+	defer b.workersMu.Unlock()
+
+	// balance probed before the account was retired must not bring its health back
+	if !b.isFundingAddress(address) {
+		return
 	}
+	b.accounts.reportBalance(address, balance)
 }
 
-// Run starts goroutines for batch processing requests.
+// Run starts goroutines for batch processing requests. Once ctx is cancelled, new requests are rejected with
+// ErrShuttingDown and Run returns after the accepted ones are processed or the drain deadline expires.
 func (b *Batcher) Run(ctx context.Context) error {
+	defer b.stopDrain()
+
 	return parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
 		spawn("closer", parallel.Fail, func(ctx context.Context) error {
 			<-ctx.Done()
+			log := logger.Get(ctx)
+			log.Info("Draining accepted requests", zap.Duration("timeout", b.drainTimeout))
 			b.close()
+			time.AfterFunc(b.drainTimeout, func() {
+				if b.drainCtx.Err() == nil {
+					log.Warn("Drain deadline expired, outcome of the pending requests is unknown")
+				}
+				b.stopDrain()
+			})
 			return errors.WithStack(ctx.Err())
 		})
 		spawn("createBatches", parallel.Fail, func(ctx context.Context) error {
-			b.createBatches()
+			b.createBatches(ctx)
 			return errors.WithStack(ctx.Err())
 		})
 		spawn("processBatches", parallel.Fail, func(ctx context.Context) error {
-			_ = parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
-				for _, fundingAddress := range b.fundingAddresses {
-					spawn(fundingAddress.String(), parallel.Continue, func(ctx context.Context) error {
-						b.processBatches(ctx, fundingAddress)
-						return nil
-					})
-				}
-				return nil
-			})
+			b.runWorkers(ctx)
 			return errors.WithStack(ctx.Err())
 		})
 		return nil
 	})
 }
 
+// runWorkers starts the worker for each funding account and returns once ctx is cancelled and all the workers
+// are done.
+func (b *Batcher) runWorkers(ctx context.Context) {
+	b.workersMu.Lock()
+	b.workersCtx = ctx
+	for _, fundingAddress := range b.fundingAddresses {
+		b.startWorker(fundingAddress)
+	}
+	b.workersMu.Unlock()
+
+	<-ctx.Done()
+
+	// workers keep draining the batches after ctx is cancelled, but no new ones are started
+	b.workersMu.Lock()
+	b.workersClosed = true
+	b.workersMu.Unlock()
+	b.workersWG.Wait()
+}
+
+// startWorker starts the worker sending the batches from the funding account, workersMu must be held.
+func (b *Batcher) startWorker(fundingAddress sdk.AccAddress) {
+	w := &worker{
+		retry:  make(chan *batch, max(b.maxPendingTxs, 1)),
+		retire: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	b.workers[fundingAddress.String()] = w
+	b.workersWG.Add(1)
+	go func() {
+		defer b.workersWG.Done()
+		defer close(w.done)
+		b.processBatches(b.workersCtx, fundingAddress, w)
+	}()
+}
+
+// stopWorker stops passing the batches for retry to the worker.
+func (b *Batcher) stopWorker(w *worker) {
+	b.workersMu.Lock()
+	defer b.workersMu.Unlock()
+
+	w.stopped = true
+}
+
+// FundingAddresses returns the addresses of the funding accounts currently used to send the batches.
+func (b *Batcher) FundingAddresses() []sdk.AccAddress {
+	b.workersMu.Lock()
+	defer b.workersMu.Unlock()
+
+	return append([]sdk.AccAddress{}, b.fundingAddresses...)
+}
+
+// AddFundingAccount starts sending the batches from the funding account. Its key must already be in the keyring.
+func (b *Batcher) AddFundingAccount(address sdk.AccAddress) error {
+	b.workersMu.Lock()
+	defer b.workersMu.Unlock()
+
+	if b.workersClosed {
+		return errors.WithStack(ErrShuttingDown)
+	}
+	if b.isFundingAddress(address) {
+		return errors.Errorf("funding account %s is already used", address)
+	}
+	b.fundingAddresses = append(b.fundingAddresses, address)
+	if b.workersCtx != nil {
+		b.startWorker(address)
+	}
+	return nil
+}
+
+// RetireFundingAccount stops sending new batches from the funding account. The returned channel is closed once
+// the batches already taken by the account are processed, so its key might be removed from the keyring.
+func (b *Batcher) RetireFundingAccount(address sdk.AccAddress) (<-chan struct{}, error) {
+	b.workersMu.Lock()
+	defer b.workersMu.Unlock()
+
+	index := -1
+	for i, fundingAddress := range b.fundingAddresses {
+		if fundingAddress.Equals(address) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.Errorf("funding account %s is not used", address)
+	}
+	if len(b.fundingAddresses) == 1 {
+		return nil, errors.New("the last funding account can't be retired")
+	}
+	b.fundingAddresses = append(b.fundingAddresses[:index:index], b.fundingAddresses[index+1:]...)
+
+	w, ok := b.workers[address.String()]
+	if !ok {
+		// worker hasn't been started yet
+		done := make(chan struct{})
+		close(done)
+		return done, nil
+	}
+	delete(b.workers, address.String())
+	close(w.retire)
+	return w.done, nil
+}
+
+// isFundingAddress tells if the address is used as the funding account, workersMu must be held.
+func (b *Batcher) isFundingAddress(address sdk.AccAddress) bool {
+	for _, fundingAddress := range b.fundingAddresses {
+		if fundingAddress.Equals(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForgetFundingAccount drops the health of the retired funding account. It returns false if the account has been
+// added back in the meantime, so its state is kept.
+func (b *Batcher) ForgetFundingAccount(address sdk.AccAddress) bool {
+	b.workersMu.Lock()
+	defer b.workersMu.Unlock()
+
+	if b.isFundingAddress(address) {
+		return false
+	}
+	b.accounts.forget(address)
+	return true
+}
+
 func (b *Batcher) close() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	if b.stopped {
 		return
 	}
-	close(b.requestBuffer)
+	close(b.closing)
+	b.lanes.close()
 	b.stopped = true
 }
 
-func (b *Batcher) isClosed() bool {
+func (b *Batcher) requestFund(
+	ctx context.Context,
+	address sdk.AccAddress,
+	amount sdk.Coins,
+	register RegisterFunc,
+	done DoneFunc,
+) error {
+	priority := PriorityFromContext(ctx)
+	// slot is acquired without holding the lock, so the requests waiting for the room in the queue don't delay
+	// the shutdown
+	if err := b.acquireSlot(ctx, priority); err != nil {
+		return err
+	}
+
+	// lock is held while enqueueing to prevent the buffer from being closed in the meantime
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return b.stopped
-}
 
-func (b *Batcher) requestFund(address sdk.AccAddress, amount sdk.Coin) (<-chan result, error) {
-	if b.isClosed() {
-		return nil, errors.New("request processor is closed")
+	if b.stopped {
+		b.lanes.release(priority)
+		return errors.WithStack(ErrShuttingDown)
 	}
 	req := request{
-		responseChan: make(chan result, 1),
+		done: done,
 		req: transferRequest{
 			destAddress: address,
 			amount:      amount,
 		},
 	}
-	b.requestBuffer <- req
-	return req.responseChan, nil
+	if register != nil {
+		id, err := register()
+		if err != nil {
+			b.lanes.release(priority)
+			return err
+		}
+		req.id = id
+	}
+	b.lanes.send(priority, req)
+	return nil
+}
+
+// acquireSlot reserves the room for the request in the queue of the priority, waiting up to the enqueue timeout
+// if the queue is full. ErrShuttingDown is returned if the batcher stops accepting requests in the meantime.
+func (b *Batcher) acquireSlot(ctx context.Context, priority Priority) error {
+	slot := b.lanes.slot(priority)
+	select {
+	case <-b.closing:
+		return errors.WithStack(ErrShuttingDown)
+	default:
+	}
+	select {
+	case slot <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if b.enqueueTimeout > 0 {
+		timer := time.NewTimer(b.enqueueTimeout)
+		defer timer.Stop()
+
+		select {
+		case slot <- struct{}{}:
+			return nil
+		case <-b.closing:
+			return errors.WithStack(ErrShuttingDown)
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	b.queueRejected.WithLabelValues(priority.String()).Inc()
+	return errors.WithStack(ErrQueueFull)
+}
+
+type batch struct {
+	requests []request
+	// failedAccounts are the funding accounts which failed to broadcast the batch.
+	failedAccounts []string
 }
 
-type batch []request
+// split splits the batch into two halves. Funding accounts which failed to broadcast the original batch are
+// not retried for the halves.
+func (ba *batch) split() (*batch, *batch) {
+	middle := len(ba.requests) / 2
+	first := &batch{
+		requests:       ba.requests[:middle],
+		failedAccounts: append([]string{}, ba.failedAccounts...),
+	}
+	second := &batch{
+		requests:       ba.requests[middle:],
+		failedAccounts: append([]string{}, ba.failedAccounts...),
+	}
+	return first, second
+}
 
-func (b *Batcher) processBatches(ctx context.Context, fromAddress sdk.AccAddress) {
+func (ba *batch) ids() []string {
+	var ids []string
+	for _, rq := range ba.requests {
+		ids = append(ids, rq.ids()...)
+	}
+	return ids
+}
+
+func (ba *batch) done(res TransferResult, err error) {
+	for _, rq := range ba.requests {
+		rq.complete(res, err)
+	}
+}
+
+// pipeline limits the number of txs broadcast from the funding account which are not committed yet.
+type pipeline struct {
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newPipeline(size int) *pipeline {
+	return &pipeline{
+		slots: make(chan struct{}, max(size, 1)),
+	}
+}
+
+// reserve reserves the slot for the tx to be broadcast, it blocks if the pipeline is full.
+func (p *pipeline) reserve() {
+	p.slots <- struct{}{}
+}
+
+// release releases the slot if the tx hasn't been broadcast.
+func (p *pipeline) release() {
+	<-p.slots
+}
+
+// await runs fn waiting for the tx in the background and releases the slot once it is done.
+func (p *pipeline) await(fn func()) {
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			p.release()
+			p.wg.Done()
+		}()
+		fn()
+	}()
+}
+
+// processBatches sends the batches from the funding account until the batches are drained or the account is retired.
+func (b *Batcher) processBatches(ctx context.Context, fromAddress sdk.AccAddress, w *worker) {
+	log := logger.Get(ctx).With(zap.Stringer("fundingAddress", fromAddress))
+	pl := newPipeline(b.maxPendingTxs)
+	defer pl.wg.Wait()
+
+	excluded := false
 	for {
-		ba, ok := <-b.batchChan
-		if !ok {
-			break
+		// once shutdown is started, all the funding accounts drain the remaining batches regardless of their health
+		if ctx.Err() == nil && !b.accounts.isHealthy(fromAddress) {
+			if !excluded {
+				log.Warn("Funding account excluded")
+				excluded = true
+			}
+			select {
+			case <-ctx.Done():
+			case <-w.retire:
+				log.Info("Funding account retired")
+				b.drainRetries(ctx, fromAddress, w, pl)
+				return
+			case ba := <-w.retry:
+				// batch passed before the account was excluded is handed over to a healthy one
+				if !b.dispatchRetry(ba) {
+					ba.done(TransferResult{}, errors.WithStack(ErrNoFundingAccount))
+				}
+			case <-time.After(accountHealthCheckInterval):
+			}
+			continue
+		}
+		if excluded {
+			log.Info("Funding account re-admitted")
+			excluded = false
 		}
 
-		b.sendBatch(ctx, fromAddress, ba)
+		var ba *batch
+		select {
+		case <-w.retire:
+			log.Info("Funding account retired")
+			b.drainRetries(ctx, fromAddress, w, pl)
+			return
+		case ba = <-w.retry:
+		case nextBatch, ok := <-b.batchChan:
+			if !ok {
+				b.drainRetries(ctx, fromAddress, w, pl)
+				return
+			}
+			ba = nextBatch
+		}
+
+		b.sendBatch(ctx, fromAddress, ba, pl)
+	}
+}
+
+// drainRetries stops the worker and sends the batches passed to it for retry before it was stopped.
+func (b *Batcher) drainRetries(ctx context.Context, fromAddress sdk.AccAddress, w *worker, pl *pipeline) {
+	b.stopWorker(w)
+	for {
+		select {
+		case ba := <-w.retry:
+			b.sendBatch(ctx, fromAddress, ba, pl)
+		default:
+			return
+		}
 	}
 }
 
-func (b *Batcher) sendBatch(ctx context.Context, fromAddress sdk.AccAddress, ba batch) {
+// sendBatch broadcasts the batch and passes it to the pipeline to wait until the tx is committed, so the next batch
+// might be broadcast from the same funding account in the meantime.
+func (b *Batcher) sendBatch(ctx context.Context, fromAddress sdk.AccAddress, ba *batch, pl *pipeline) {
 	log := logger.Get(ctx)
-	ctx = logger.WithLogger(context.Background(), log)
-	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	// requests are not cancelled on shutdown but only once the drain deadline expires
+	ctx = logger.WithLogger(b.drainCtx, log)
+	if ctx.Err() != nil {
+		ba.done(TransferResult{}, errors.WithStack(ErrShuttingDown))
+		return
+	}
+
+	pl.reserve()
+	//nolint:contextcheck // We don't want to cancel requests on shutdown sequence
+	txHash, err := b.broadcast(ctx, fromAddress, ba)
+	res := TransferResult{
+		TxHash:         txHash,
+		FundingAddress: fromAddress,
+	}
+	if err != nil {
+		pl.release()
+		switch {
+		case errors.Is(err, ErrOutcomeUnknown):
+			// the tx might be committed, so the batch must not be sent from another funding account
+		case ctx.Err() != nil:
+			err = errors.Wrapf(ErrShuttingDown, "broadcasting interrupted by drain deadline: %s", err)
+		case isRequestError(err) && len(ba.requests) > 1:
+			// failure is caused by some of the requests, so the batch is bisected to let the valid requests succeed
+			// and to find the offending ones
+			log.Warn("Batch rejected, bisecting", zap.Int("requests", len(ba.requests)), zap.Error(err))
+			first, second := ba.split()
+			//nolint:contextcheck // We don't want to cancel requests on shutdown sequence
+			b.sendBatch(ctx, fromAddress, first, pl)
+			//nolint:contextcheck // We don't want to cancel requests on shutdown sequence
+			b.sendBatch(ctx, fromAddress, second, pl)
+			return
+		case isRequestError(err):
+		default:
+			// the tx has been rejected by the node, so it is safe to send the batch from another funding account
+			if b.accounts.reportFailure(fromAddress, err) && b.retry(ba, fromAddress) {
+				log.Warn("Batch failed, retrying on another funding account",
+					zap.Stringer("fundingAddress", fromAddress), zap.Error(err))
+				return
+			}
+		}
+		ba.done(res, err)
+		return
+	}
+
+	pl.await(func() {
+		// progress is reported in the background, so the next batch might be broadcast in the meantime
+		b.reportProgress(ctx, StageBroadcast, res, ba)
+		b.awaitBatch(ctx, ba, res)
+	})
+}
+
+func (b *Batcher) reportProgress(ctx context.Context, stage Stage, res TransferResult, ba *batch) {
+	if b.progress != nil {
+		b.progress(ctx, stage, res, ba.ids())
+	}
+}
+
+func (b *Batcher) awaitBatch(ctx context.Context, ba *batch, res TransferResult) {
+	ctx, cancel := context.WithTimeout(ctx, batchRequestTimeout)
 	defer cancel()
 
-	rsp := result{}
-	requests := []transferRequest{}
-	for _, r := range ba {
+	err := b.client.AwaitTx(ctx, res.TxHash)
+	switch {
+	case err != nil && b.drainCtx.Err() != nil:
+		err = errors.Wrapf(ErrOutcomeUnknown, "awaiting tx interrupted by drain deadline: %s", err)
+	case err != nil:
+		b.accounts.reportFailure(res.FundingAddress, err)
+		// only the tx executed with an error code is known to be failed, the one not found might still be committed
+		if !isExecutionError(err) {
+			err = errors.Wrapf(ErrOutcomeUnknown, "awaiting tx failed: %s", err)
+		}
+	default:
+		b.accounts.reportSuccess(res.FundingAddress)
+	}
+
+	ba.done(res, err)
+}
+
+// broadcast broadcasts the tx transferring the tokens requested in the batch. Transient failures are retried
+// with exponential backoff. If it is unknown whether the node accepted the tx, it is broadcast again from the same
+// account and sequence, and ErrOutcomeUnknown is returned if that doesn't succeed.
+func (b *Batcher) broadcast(ctx context.Context, fromAddress sdk.AccAddress, ba *batch) (string, error) {
+	requests := make([]transferRequest, 0, len(ba.requests))
+	for _, r := range ba.requests {
 		requests = append(requests, r.req)
 	}
 
-	//nolint:contextcheck // We don't want to cancel requests on shutdown sequence
-	txHash, err := b.client.TransferToken(ctx, fromAddress, requests...)
-	if err != nil {
-		rsp.err = err
-	} else {
-		rsp.txHash = txHash
+	// hash of the tx which has been sent to the node, but it is unknown whether it was accepted
+	var ambiguousTxHash string
+	fail := func(err error) (string, error) {
+		if ambiguousTxHash != "" {
+			return ambiguousTxHash, errors.Wrapf(ErrOutcomeUnknown, "broadcasting tx %s failed: %s", ambiguousTxHash, err)
+		}
+		return "", err
 	}
 
-	for _, rq := range ba {
-		rq.responseChan <- rsp
+	backoff := b.retryBackoff
+	for attempt := 0; ; attempt++ {
+		txHash, err := b.transferToken(ctx, fromAddress, requests)
+		switch {
+		case err == nil:
+			return txHash, nil
+		case isAmbiguousError(err):
+			// the account sequence is kept, so the tx broadcast again can't be committed together with this one
+			ambiguousTxHash = txHash
+		case ambiguousTxHash != "" && !isNotBroadcastError(err):
+			// tx might have been rejected because the one broadcast before has been accepted
+			return fail(err)
+		case !isTransientError(err):
+			return fail(err)
+		default:
+		}
+		if attempt >= b.maxRetries {
+			return fail(err)
+		}
+		logger.Get(ctx).Warn("Broadcasting batch failed, retrying",
+			zap.Stringer("fundingAddress", fromAddress),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return fail(errors.WithStack(ctx.Err()))
+		case <-time.After(backoff):
+		}
+		backoff *= 2
 	}
 }
 
-func (b *Batcher) createBatches() {
-	var ba batch
+func (b *Batcher) transferToken(
+	ctx context.Context,
+	fromAddress sdk.AccAddress,
+	requests []transferRequest,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, batchRequestTimeout)
+	defer cancel()
+
+	return b.client.TransferToken(ctx, fromAddress, requests...)
+}
+
+// retry passes the batch to be sent from another funding account. Batch is attempted at most once per funding account,
+// so false is returned once the attempts are exhausted or if there is no other funding account available.
+func (b *Batcher) retry(ba *batch, fromAddress sdk.AccAddress) bool {
+	ba.failedAccounts = append(ba.failedAccounts, fromAddress.String())
+	return b.dispatchRetry(ba)
+}
+
+// dispatchRetry passes the batch to the healthy funding account which hasn't failed to send it yet.
+func (b *Batcher) dispatchRetry(ba *batch) bool {
+	b.workersMu.Lock()
+	defer b.workersMu.Unlock()
+
+	for _, fundingAddress := range b.fundingAddresses {
+		key := fundingAddress.String()
+		w, ok := b.workers[key]
+		if !ok || w.stopped || slices.Contains(ba.failedAccounts, key) {
+			continue
+		}
+		// once shutdown is started, all the funding accounts drain the batches regardless of their health
+		if !b.workersClosed && !b.accounts.isHealthy(fundingAddress) {
+			continue
+		}
+		select {
+		case w.retry <- ba:
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// hasHealthyAccount tells if any of the funding accounts might be used to send the batch.
+func (b *Batcher) hasHealthyAccount() bool {
+	for _, fundingAddress := range b.FundingAddresses() {
+		if b.accounts.isHealthy(fundingAddress) {
+			return true
+		}
+	}
+	return false
+}
+
+// createBatches seals the batch once it reaches the maximum size or gas, or once the linger period started by its
+// first request expires. Batched stage is reported before the batch is passed to the funding accounts, so it is
+// recorded before the batch is broadcast, while the previous batches are being sent.
+func (b *Batcher) createBatches(ctx context.Context) {
+	defer close(b.batchChan)
+
+	var next *request
 	for {
-		req, ok := <-b.requestBuffer
-		if ok {
-			ba = append(ba, req)
+		if next == nil {
+			req, state := b.lanes.receive(nil)
+			if state == laneClosed {
+				return
+			}
+			next = &req
 		}
 
-		if (len(ba) >= b.batchSize || len(b.requestBuffer) == 0 || !ok) && len(ba) > 0 {
-			b.batchChan <- ba
-			ba = batch{}
+		req := *next
+		next = nil
+		gas := b.newBatchGas().add(req.req)
+		if !b.fitsGas(gas) {
+			req.complete(TransferResult{}, errors.WithStack(ErrGasLimitExceeded))
+			continue
 		}
+		ba := &batch{requests: []request{req}}
+		closed := b.fillBatch(ba, gas, &next)
 
-		if !ok {
-			break
+		b.batchSizes.Observe(float64(len(ba.requests)))
+		b.dispatch(ctx, ba)
+		if closed {
+			return
+		}
+	}
+}
+
+// dispatch passes the batch to the funding accounts. If all of them are excluded, the batch fails immediately
+// instead of waiting until any of them is re-admitted.
+func (b *Batcher) dispatch(ctx context.Context, ba *batch) {
+	reported := false
+	for {
+		// once shutdown is started, all the funding accounts drain the batches regardless of their health
+		if ctx.Err() == nil && !b.hasHealthyAccount() {
+			logger.Get(ctx).Warn("All funding accounts are excluded, batch rejected",
+				zap.Int("requests", len(ba.requests)))
+			ba.done(TransferResult{}, errors.WithStack(ErrNoFundingAccount))
+			return
+		}
+		if !reported {
+			b.reportProgress(ctx, StageBatched, TransferResult{}, ba)
+			reported = true
 		}
+		select {
+		case b.batchChan <- ba:
+			return
+		case <-time.After(accountHealthCheckInterval):
+		}
+	}
+}
+
+// fillBatch adds requests to the batch until it is sealed. Request which doesn't fit into the batch because of gas
+// limit is stored in next. True is returned if the request queue is closed.
+func (b *Batcher) fillBatch(ba *batch, gas batchGas, next **request) bool {
+	// without linger period, the batch is sealed as soon as there are no requests waiting
+	lingerC := closedTimeChan
+	if b.linger > 0 {
+		timer := time.NewTimer(b.linger)
+		defer timer.Stop()
+		lingerC = timer.C
+	}
+
+	for len(ba.requests) < b.batchSize {
+		req, state := b.lanes.receive(lingerC)
+		switch state {
+		case laneTimedOut:
+			return false
+		case laneClosed:
+			return true
+		default:
+		}
+		switch b.addDuplicate(ba, req) {
+		case duplicateHandled:
+			continue
+		case duplicateDeferred:
+			*next = &req
+			return false
+		default:
+		}
+		reqGas := gas.add(req.req)
+		if !b.fitsGas(reqGas) {
+			*next = &req
+			return false
+		}
+		gas = reqGas
+		ba.requests = append(ba.requests, req)
+	}
+	return false
+}
+
+type duplicateResult int
+
+const (
+	duplicateNone duplicateResult = iota
+	duplicateHandled
+	duplicateDeferred
+)
+
+// addDuplicate handles the request if the batch already contains the request for the same destination address.
+func (b *Batcher) addDuplicate(ba *batch, req request) duplicateResult {
+	if b.duplicates == DuplicatesAllow {
+		return duplicateNone
+	}
+	for i, r := range ba.requests {
+		if !r.req.destAddress.Equals(req.req.destAddress) {
+			continue
+		}
+		if b.duplicates == DuplicatesReject {
+			req.complete(TransferResult{}, errors.WithStack(ErrDuplicateRequest))
+			return duplicateHandled
+		}
+		if !r.req.amount.Equal(req.req.amount) {
+			return duplicateDeferred
+		}
+		ba.requests[i].coalesced = append(ba.requests[i].coalesced, req)
+		return duplicateHandled
+	}
+	return duplicateNone
+}
+
+// newBatchGas returns the gas of the empty batch.
+func (b *Batcher) newBatchGas() batchGas {
+	if b.maxGas == 0 {
+		return batchGas{}
+	}
+	// gas doesn't depend on the sender, so any funding account is used as the input
+	return newBatchGas(b.FundingAddresses()[0])
+}
+
+func (b *Batcher) fitsGas(gas batchGas) bool {
+	return b.maxGas == 0 || gas.gas(b.gasEstimator) <= b.maxGas
+}
+
+var queueDepthDesc = prometheus.NewDesc(
+	"queue_depth", "Number of requests waiting to be batched", []string{"lane"}, nil)
+
+// Describe implements prometheus.Collector interface.
+func (b *Batcher) Describe(ch chan<- *prometheus.Desc) {
+	b.batchSizes.Describe(ch)
+	ch <- queueDepthDesc
+	b.queueRejected.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface.
+func (b *Batcher) Collect(ch chan<- prometheus.Metric) {
+	b.batchSizes.Collect(ch)
+	for p := range priorityCount {
+		ch <- prometheus.MustNewConstMetric(
+			queueDepthDesc, prometheus.GaugeValue, float64(b.lanes.depth(p)), p.String())
 	}
-	close(b.batchChan)
+	b.queueRejected.Collect(ch)
 }
@@ -2,10 +2,14 @@ package coreum
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
 
 	"github.com/cosmos/cosmos-sdk/client/tx"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
 	"github.com/CoreumFoundation/coreum/v5/pkg/client"
@@ -14,27 +18,35 @@ import (
 )
 
 // New returns an instance of the Client interface.
-func New(network config.NetworkConfig, clientCtx client.Context, txf client.Factory) Client {
+func New(network config.NetworkConfig, nodes *NodePool, txf client.Factory, gasEstimator GasEstimator) Client {
 	return Client{
-		network:   network,
-		clientCtx: clientCtx,
-		txf:       txf,
+		network:      network,
+		nodes:        nodes,
+		txf:          txf,
+		sequences:    newSequenceCache(),
+		gasEstimator: gasEstimator,
+		gasPrice:     &gasPriceCache{},
 	}
 }
 
 // Client is used to communicate with coreum blockchain.
 type Client struct {
-	clientCtx client.Context
-	network   config.NetworkConfig
-	txf       tx.Factory
+	nodes        *NodePool
+	network      config.NetworkConfig
+	txf          tx.Factory
+	sequences    *sequenceCache
+	gasEstimator GasEstimator
+	gasPrice     *gasPriceCache
 }
 
 type transferRequest struct {
-	amount      sdk.Coin
+	amount      sdk.Coins
 	destAddress sdk.AccAddress
 }
 
-// TransferToken transfers amount to a list of destination addresses in single tx.
+// TransferToken broadcasts the tx transferring amount to a list of destination addresses. It returns once the tx
+// is accepted to the mempool, AwaitTx must be used to wait until the tx is committed. Account number and sequence
+// of the funding account are cached, so many txs might be broadcast from the same account before they are committed.
 func (c Client) TransferToken(
 	ctx context.Context,
 	fromAddress sdk.AccAddress,
@@ -47,28 +59,137 @@ func (c Client) TransferToken(
 	log := logger.Get(ctx).With(zap.Stringer("fromAddress", fromAddress), zap.Strings("toAddresses", toAddressList))
 	log.Info("Sending tokens")
 
+	msg := newMultiSendMsg(fromAddress, requests)
+	// key is found in the keyring by the address, so the names of the keys don't matter
+	clientCtx := c.nodes.ClientContext().
+		WithFromAddress(fromAddress).
+		WithAwaitTx(false)
+
+	acc := c.sequences.account(fromAddress.String())
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	if !acc.known {
+		info, err := client.GetAccountInfo(ctx, clientCtx, fromAddress)
+		if err != nil {
+			c.nodes.ReportError(ctx, clientCtx, err)
+			return "", notBroadcastError{error: err}
+		}
+		acc.accountNumber = info.GetAccountNumber()
+		acc.sequence = info.GetSequence()
+		acc.known = true
+	}
+
+	gasPrice, err := c.gasPrice.get(ctx, clientCtx)
+	if err != nil {
+		c.nodes.ReportError(ctx, clientCtx, err)
+		return "", notBroadcastError{error: err}
+	}
+	gas, err := c.gasEstimator.Estimate(msg)
+	if err != nil {
+		return "", notBroadcastError{error: err}
+	}
+
+	txf := c.txf.
+		WithAccountNumber(acc.accountNumber).
+		WithSequence(acc.sequence).
+		WithGasPrices(gasPrice.String())
+	txBytes, err := signTx(ctx, clientCtx, txf.WithGas(gas), msg)
+	if err != nil {
+		return "", notBroadcastError{error: err}
+	}
+	// estimation is verified against the real size of the tx
+	requiredGas, err := c.gasEstimator.estimateTx(msg, uint64(len(txBytes)))
+	if err != nil {
+		return "", notBroadcastError{error: err}
+	}
+	if requiredGas > gas {
+		txBytes, err = signTx(ctx, clientCtx, txf.WithGas(requiredGas), msg)
+		if err != nil {
+			return "", notBroadcastError{error: err}
+		}
+	}
+	txHash := fmt.Sprintf("%X", sha256.Sum256(txBytes))
+
+	// tx is already in the mempool cache if it has been accepted by the node on the previous attempt
+	if _, err := client.BroadcastRawTx(ctx, clientCtx, txBytes); err != nil &&
+		!errorMatches(err, cosmoserrors.ErrTxInMempoolCache) {
+		acc.reportError(err)
+		c.nodes.ReportError(ctx, clientCtx, err)
+		if errorMatches(err, cosmoserrors.ErrInsufficientFee) {
+			c.gasPrice.invalidate()
+		}
+		// hash is returned, so the outcome of the tx might be checked if it is unknown whether it was accepted
+		return txHash, err
+	}
+	acc.sequence++
+
+	log.Info("Tokens sent", zap.String("txHash", txHash))
+	return txHash, nil
+}
+
+// signTx builds and signs the tx, returning its encoded bytes.
+func signTx(ctx context.Context, clientCtx client.Context, txf tx.Factory, msg sdk.Msg) ([]byte, error) {
+	unsignedTx, err := client.GenerateUnsignedTx(ctx, clientCtx, txf, msg)
+	if err != nil {
+		return nil, err
+	}
+	key, err := clientCtx.Keyring().KeyByAddress(clientCtx.FromAddress())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key by the address %q from the keyring", clientCtx.FromAddress())
+	}
+	if err := tx.Sign(ctx, txf, key.Name, unsignedTx, true); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	txBytes, err := clientCtx.TxConfig().TxEncoder()(unsignedTx.GetTx())
+	return txBytes, errors.WithStack(err)
+}
+
+// ForgetAccount drops the cached sequence of the funding account which is no longer used.
+func (c Client) ForgetAccount(address sdk.AccAddress) {
+	c.sequences.forget(address.String())
+}
+
+// AwaitTx waits until the tx is committed to the block.
+func (c Client) AwaitTx(ctx context.Context, txHash string) error {
+	clientCtx := c.nodes.ClientContext()
+	_, err := client.AwaitTx(ctx, clientCtx, txHash)
+	if err != nil {
+		c.nodes.ReportError(ctx, clientCtx, err)
+		return err
+	}
+
+	logger.Get(ctx).Info("Tokens transfer committed", zap.String("txHash", txHash))
+	return nil
+}
+
+// Balance returns the balance of the denom held by the address.
+func (c Client) Balance(ctx context.Context, address sdk.AccAddress, denom string) (sdk.Coin, error) {
+	clientCtx := c.nodes.ClientContext()
+	resp, err := banktypes.NewQueryClient(clientCtx).Balance(ctx, &banktypes.QueryBalanceRequest{
+		Address: address.String(),
+		Denom:   denom,
+	})
+	if err != nil {
+		c.nodes.ReportError(ctx, clientCtx, err)
+		return sdk.Coin{}, errors.WithStack(err)
+	}
+	return *resp.Balance, nil
+}
+
+func newMultiSendMsg(fromAddress sdk.AccAddress, requests []transferRequest) *banktypes.MsgMultiSend {
 	msg := &banktypes.MsgMultiSend{}
 	sum := sdk.NewCoins()
 	for _, rq := range requests {
-		sum = sum.Add(rq.amount)
+		sum = sum.Add(rq.amount...)
 		msg.Outputs = append(msg.Outputs, banktypes.Output{
 			Address: rq.destAddress.String(),
-			Coins:   sdk.NewCoins(rq.amount),
+			Coins:   rq.amount,
 		})
 	}
 	msg.Inputs = []banktypes.Input{{
 		Address: fromAddress.String(),
 		Coins:   sum,
 	}}
-	clientCtx := c.clientCtx.
-		WithFromName(fromAddress.String()).
-		WithFromAddress(fromAddress)
-
-	result, err := client.BroadcastTx(ctx, clientCtx, c.txf.WithSimulateAndExecute(true), msg)
-	if err != nil {
-		return "", err
-	}
-
-	log.Info("Tokens sent")
-	return result.TxHash, nil
+	return msg
 }
@@ -0,0 +1,160 @@
+package coreum
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+	"github.com/CoreumFoundation/coreum/v5/x/deterministicgas"
+)
+
+const (
+	// txOverheadBytes is the upper bound of the tx size excluding its messages, used to estimate the gas before
+	// the tx is signed.
+	txOverheadBytes = 400
+	// gasSafetyMarginPercent is added to the estimated gas to cover the differences between the estimated and
+	// the real size of the tx.
+	gasSafetyMarginPercent = 10
+	// gasPriceTTL is how long the gas price queried from the chain is used before it is queried again.
+	gasPriceTTL = 10 * time.Second
+)
+
+// deterministicGasConfig is used to compute the gas of MsgMultiSend which doesn't need to be simulated.
+var deterministicGasConfig = deterministicgas.DefaultConfig()
+
+// GasEstimator computes the gas required by the tx containing MsgMultiSend. Gas consumed by MsgMultiSend is
+// deterministic, so the tx doesn't need to be simulated.
+type GasEstimator struct {
+	txSizeCostPerByte uint64
+}
+
+// NewGasEstimator returns the gas estimator charging txSizeCostPerByte for each byte above the free limit.
+func NewGasEstimator(txSizeCostPerByte uint64) GasEstimator {
+	return GasEstimator{
+		txSizeCostPerByte: txSizeCostPerByte,
+	}
+}
+
+// DefaultGasEstimator returns the gas estimator using the default auth params.
+func DefaultGasEstimator() GasEstimator {
+	return NewGasEstimator(authtypes.DefaultTxSizeCostPerByte)
+}
+
+// QueryGasEstimator returns the gas estimator using the auth params of the chain.
+func QueryGasEstimator(ctx context.Context, clientCtx client.Context) (GasEstimator, error) {
+	res, err := authtypes.NewQueryClient(clientCtx).Params(ctx, &authtypes.QueryParamsRequest{})
+	if err != nil {
+		return GasEstimator{}, errors.Wrap(err, "failed to query auth params")
+	}
+	return NewGasEstimator(res.Params.TxSizeCostPerByte), nil
+}
+
+// Estimate returns the gas required by the tx containing the message, before the tx is built.
+func (e GasEstimator) Estimate(msg *banktypes.MsgMultiSend) (uint64, error) {
+	return e.estimateTx(msg, uint64(msg.Size())+txOverheadBytes)
+}
+
+// estimateTx returns the gas required by the tx of the size containing the message.
+func (e GasEstimator) estimateTx(msg *banktypes.MsgMultiSend, txSize uint64) (uint64, error) {
+	msgGas, ok := deterministicGasConfig.GasRequiredByMessage(msg)
+	if !ok {
+		return 0, errors.New("gas of MsgMultiSend is expected to be deterministic")
+	}
+	return e.txGas(msgGas, txSize), nil
+}
+
+// txGas returns the gas required by the tx of the size, including the gas of its message.
+func (e GasEstimator) txGas(msgGas, txSize uint64) uint64 {
+	gas := deterministicGasConfig.FixedGas + msgGas
+	// bytes above the free limit are charged
+	if txSize > deterministicGasConfig.FreeBytes {
+		gas += (txSize - deterministicGasConfig.FreeBytes) * e.txSizeCostPerByte
+	}
+	return gas + gas*gasSafetyMarginPercent/100
+}
+
+// batchGas accumulates the operations and the size of MsgMultiSend as the requests are added to the batch, so
+// the gas is computed without building the message each time.
+type batchGas struct {
+	inputAddress     string
+	inputCoins       sdk.Coins
+	outputOperations uint64
+	outputsSize      uint64
+}
+
+func newBatchGas(fromAddress sdk.AccAddress) batchGas {
+	return batchGas{
+		inputAddress: fromAddress.String(),
+	}
+}
+
+// add returns the gas of the batch with the request added.
+func (g batchGas) add(rq transferRequest) batchGas {
+	output := banktypes.Output{
+		Address: rq.destAddress.String(),
+		Coins:   rq.amount,
+	}
+	g.inputCoins = g.inputCoins.Add(rq.amount...)
+	g.outputOperations += uint64(len(rq.amount))
+	g.outputsSize += messageFieldSize(output.Size())
+	return g
+}
+
+// gas returns the gas required by the tx sending the batch, the same as returned by GasEstimator.Estimate
+// for the message.
+func (g batchGas) gas(estimator GasEstimator) uint64 {
+	input := banktypes.Input{
+		Address: g.inputAddress,
+		Coins:   g.inputCoins,
+	}
+	// at least one input and one output operation is charged
+	operations := max(uint64(len(g.inputCoins))+g.outputOperations, 2)
+	msgSize := messageFieldSize(input.Size()) + g.outputsSize
+	return estimator.txGas(operations*deterministicgas.BankMultiSendPerOperationsGas, msgSize+txOverheadBytes)
+}
+
+// messageFieldSize returns the number of bytes taken by the embedded message of the size, including its tag.
+func messageFieldSize(size int) uint64 {
+	return uint64(1 + protowire.SizeBytes(size))
+}
+
+// gasPriceCache stores the gas price, so it isn't queried before each tx is broadcast. Broadcasting the tx again
+// with the same gas price produces the same tx, recognized by the node if it has been accepted before.
+type gasPriceCache struct {
+	mu        sync.Mutex
+	price     sdk.DecCoin
+	fetchedAt time.Time
+}
+
+// get returns the cached gas price, querying it if it is expired.
+func (c *gasPriceCache) get(ctx context.Context, clientCtx client.Context) (sdk.DecCoin, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < gasPriceTTL {
+		return c.price, nil
+	}
+	price, err := client.GetGasPrice(ctx, clientCtx)
+	if err != nil {
+		return sdk.DecCoin{}, err
+	}
+	price.Amount = price.Amount.Mul(clientCtx.GasPriceAdjustment())
+	c.price = price
+	c.fetchedAt = time.Now()
+	return price, nil
+}
+
+// invalidate forces the gas price to be queried before the next tx is broadcast.
+func (c *gasPriceCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fetchedAt = time.Time{}
+}
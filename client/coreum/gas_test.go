@@ -0,0 +1,72 @@
+package coreum
+
+import (
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGasEstimatorEstimate(t *testing.T) {
+	requireT := require.New(t)
+
+	multiSend := func(outputs int) *banktypes.MsgMultiSend {
+		coins := sdk.NewCoins(sdk.NewInt64Coin("ucore", 100))
+		msg := &banktypes.MsgMultiSend{
+			Inputs: []banktypes.Input{{
+				Address: sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()).String(),
+				Coins:   coins,
+			}},
+		}
+		for range outputs {
+			msg.Outputs = append(msg.Outputs, banktypes.Output{
+				Address: sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()).String(),
+				Coins:   coins,
+			})
+		}
+		return msg
+	}
+
+	estimate := func(estimator GasEstimator, msg *banktypes.MsgMultiSend) uint64 {
+		gas, err := estimator.Estimate(msg)
+		requireT.NoError(err)
+		return gas
+	}
+
+	// fixed gas and one input and one output, with the safety margin
+	requireT.EqualValues((65000+2*35000)*110/100, estimate(DefaultGasEstimator(), multiSend(1)))
+	requireT.EqualValues((65000+11*35000)*110/100, estimate(DefaultGasEstimator(), multiSend(10)))
+	// bytes above the free limit are charged
+	requireT.Greater(estimate(DefaultGasEstimator(), multiSend(100)), uint64((65000+101*35000)*110/100))
+	requireT.Greater(estimate(NewGasEstimator(20), multiSend(100)), estimate(DefaultGasEstimator(), multiSend(100)))
+}
+
+func TestBatchGas(t *testing.T) {
+	requireT := require.New(t)
+
+	fromAddress := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	estimator := DefaultGasEstimator()
+	amounts := []sdk.Coins{
+		sdk.NewCoins(sdk.NewInt64Coin("ucore", 100)),
+		sdk.NewCoins(sdk.NewInt64Coin("ucore", 123456789)),
+		sdk.NewCoins(sdk.NewInt64Coin("ucore", 1), sdk.NewInt64Coin("uother", 5)),
+	}
+
+	gas := newBatchGas(fromAddress)
+	requests := []transferRequest{}
+	// enough requests to exceed the free bytes
+	for i := range 100 {
+		rq := transferRequest{
+			amount:      amounts[i%len(amounts)],
+			destAddress: sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()),
+		}
+		gas = gas.add(rq)
+		requests = append(requests, rq)
+
+		expected, err := estimator.Estimate(newMultiSendMsg(fromAddress, requests))
+		requireT.NoError(err)
+		requireT.Equal(expected, gas.gas(estimator))
+	}
+}
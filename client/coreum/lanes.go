@@ -0,0 +1,186 @@
+package coreum
+
+import (
+	"context"
+	"time"
+)
+
+// Priority is the priority of the transfer request. Requests with higher priority are batched first.
+type Priority int
+
+// Supported priorities, from the highest to the lowest.
+const (
+	PriorityInternal Priority = iota
+	PriorityAuthenticated
+	PriorityPublic
+
+	priorityCount
+)
+
+var priorityNames = [priorityCount]string{"internal", "authenticated", "public"}
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	if p < 0 || p >= priorityCount {
+		return "unknown"
+	}
+	return priorityNames[p]
+}
+
+// LaneWeights defines how many requests of each priority are batched in a row before a request of lower
+// priority is taken, if there is any waiting.
+type LaneWeights [priorityCount]int
+
+// DefaultLaneWeights returns the default lane weights.
+func DefaultLaneWeights() LaneWeights {
+	return LaneWeights{6, 3, 1}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns the context carrying the priority of the transfer requests sent with it.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority stored in the context, PriorityPublic is returned if there is none.
+func PriorityFromContext(ctx context.Context) Priority {
+	priority, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok || priority < 0 || priority >= priorityCount {
+		return PriorityPublic
+	}
+	return priority
+}
+
+type laneState int
+
+const (
+	laneReceived laneState = iota
+	laneTimedOut
+	laneClosed
+)
+
+// lanes are the request queues of all the priorities, drained using weighted round-robin, so the requests
+// of the higher priority are taken first while the lower ones still make progress. Requests are taken
+// by a single consumer.
+type lanes struct {
+	queues [priorityCount]chan request
+	// slots limit the number of requests in each queue, including the ones reserved but not sent yet,
+	// so sending the request holding the slot never blocks.
+	slots   [priorityCount]chan struct{}
+	weights LaneWeights
+	credits LaneWeights
+	closed  [priorityCount]bool
+}
+
+func newLanes(depth int, weights LaneWeights) *lanes {
+	l := &lanes{
+		weights: weights,
+	}
+	for p := range l.queues {
+		l.queues[p] = make(chan request, depth)
+		l.slots[p] = make(chan struct{}, depth)
+		// lane without weight would never be drained
+		l.weights[p] = max(l.weights[p], 1)
+	}
+	l.credits = l.weights
+	return l
+}
+
+// slot returns the semaphore which must be acquired before the request is sent to the lane. Slot is released once
+// the request is received from the lane.
+func (l *lanes) slot(priority Priority) chan struct{} {
+	return l.slots[priority]
+}
+
+// release releases the slot acquired for the request which is not sent to the lane.
+func (l *lanes) release(priority Priority) {
+	<-l.slots[priority]
+}
+
+// send sends the request to the lane. The slot must be acquired before.
+func (l *lanes) send(priority Priority, req request) {
+	l.queues[priority] <- req
+}
+
+func (l *lanes) close() {
+	for _, q := range l.queues {
+		close(q)
+	}
+}
+
+// take returns the waiting request from the lane selected by the weights. False is returned if there is
+// no request waiting.
+func (l *lanes) take() (request, bool) {
+	for range 2 {
+		for p, q := range l.queues {
+			if l.closed[p] || l.credits[p] == 0 {
+				continue
+			}
+			select {
+			case req, ok := <-q:
+				if !ok {
+					l.closed[p] = true
+					continue
+				}
+				l.release(Priority(p))
+				l.credits[p]--
+				return req, true
+			default:
+			}
+		}
+		// all the lanes having requests waiting used their credits
+		l.credits = l.weights
+	}
+	return request{}, false
+}
+
+// receive returns the next request. If there is none waiting, it waits until any lane receives one, all
+// the lanes are closed or timeout expires. Nil timeout waits forever.
+func (l *lanes) receive(timeout <-chan time.Time) (request, laneState) {
+	for {
+		if req, ok := l.take(); ok {
+			return req, laneReceived
+		}
+
+		var queues [priorityCount]chan request
+		open := false
+		for p, q := range l.queues {
+			if !l.closed[p] {
+				queues[p] = q
+				open = true
+			}
+		}
+		if !open {
+			return request{}, laneClosed
+		}
+
+		var (
+			req      request
+			ok       bool
+			priority Priority
+		)
+		select {
+		case req, ok = <-queues[PriorityInternal]:
+			priority = PriorityInternal
+		case req, ok = <-queues[PriorityAuthenticated]:
+			priority = PriorityAuthenticated
+		case req, ok = <-queues[PriorityPublic]:
+			priority = PriorityPublic
+		case <-timeout:
+			return request{}, laneTimedOut
+		}
+		if !ok {
+			l.closed[priority] = true
+			continue
+		}
+		l.release(priority)
+		l.credits[priority] = max(l.credits[priority]-1, 0)
+		return req, laneReceived
+	}
+}
+
+// depth returns the number of requests waiting in the lane.
+func (l *lanes) depth(priority Priority) int {
+	return len(l.queues[priority])
+}
@@ -0,0 +1,71 @@
+package coreum
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLanesWeightedDraining(t *testing.T) {
+	requireT := require.New(t)
+
+	l := newLanes(10, LaneWeights{2, 1, 1})
+	enqueue := func(priority Priority, count int) {
+		for range count {
+			l.slot(priority) <- struct{}{}
+			l.send(priority, request{req: transferRequest{
+				amount: sdk.NewCoins(sdk.NewCoin("test-denom", sdkmath.NewInt(int64(priority)+1))),
+			}})
+		}
+	}
+	enqueue(PriorityPublic, 3)
+	enqueue(PriorityAuthenticated, 3)
+	enqueue(PriorityInternal, 5)
+
+	var order []Priority
+	for {
+		req, state := l.receive(closedTimeChan)
+		if state != laneReceived {
+			break
+		}
+		order = append(order, Priority(req.req.amount[0].Amount.Int64()-1))
+	}
+
+	requireT.Equal([]Priority{
+		PriorityInternal, PriorityInternal, PriorityAuthenticated, PriorityPublic,
+		PriorityInternal, PriorityInternal, PriorityAuthenticated, PriorityPublic,
+		PriorityInternal, PriorityAuthenticated, PriorityPublic,
+	}, order)
+}
+
+func TestLanesClose(t *testing.T) {
+	requireT := require.New(t)
+
+	l := newLanes(10, DefaultLaneWeights())
+	l.slot(PriorityPublic) <- struct{}{}
+	l.send(PriorityPublic, request{})
+	l.close()
+
+	// requests waiting in the queue are still received after it is closed
+	_, state := l.receive(nil)
+	requireT.Equal(laneReceived, state)
+	_, state = l.receive(nil)
+	requireT.Equal(laneClosed, state)
+}
+
+func TestLanesReceiveTimeout(t *testing.T) {
+	l := newLanes(10, DefaultLaneWeights())
+	_, state := l.receive(time.After(10 * time.Millisecond))
+	require.Equal(t, laneTimedOut, state)
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	requireT := require.New(t)
+
+	requireT.Equal(PriorityPublic, PriorityFromContext(context.Background()))
+	requireT.Equal(PriorityInternal, PriorityFromContext(WithPriority(context.Background(), PriorityInternal)))
+}
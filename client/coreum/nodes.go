@@ -0,0 +1,268 @@
+package coreum
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/cmtservice"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+)
+
+const nodeRequestTimeout = 5 * time.Second
+
+// Node is the gRPC endpoint of the coreum node.
+type Node struct {
+	URL  string
+	Conn *grpc.ClientConn
+}
+
+// NodeStatus describes the state of the node observed by the last health check.
+type NodeStatus struct {
+	URL     string
+	Active  bool
+	Healthy bool
+	Syncing bool
+	Height  int64
+	Error   string
+}
+
+type nodeHealth struct {
+	reachable bool
+	syncing   bool
+	height    int64
+	err       error
+}
+
+// NodePool keeps track of the health of the nodes and selects the one used to communicate with the chain.
+// The active node is changed only if it becomes unhealthy.
+type NodePool struct {
+	clientCtx           client.Context
+	nodes               []Node
+	healthCheckInterval time.Duration
+	maxBlockLag         int64
+
+	mu      sync.RWMutex
+	active  int
+	health  []nodeHealth
+	healthy []bool
+}
+
+// NewNodePool returns new node pool. Node is considered healthy if it responds, is not syncing and its height
+// is not lower than maxBlockLag blocks compared to the highest node. Until the first health check is done,
+// all the nodes are considered healthy.
+func NewNodePool(
+	clientCtx client.Context,
+	nodes []Node,
+	healthCheckInterval time.Duration,
+	maxBlockLag int64,
+) *NodePool {
+	healthy := make([]bool, len(nodes))
+	for i := range healthy {
+		healthy[i] = true
+	}
+	return &NodePool{
+		clientCtx:           clientCtx,
+		nodes:               nodes,
+		healthCheckInterval: healthCheckInterval,
+		maxBlockLag:         maxBlockLag,
+		health:              make([]nodeHealth, len(nodes)),
+		healthy:             healthy,
+	}
+}
+
+// ClientContext returns the client context communicating with the active node.
+func (p *NodePool) ClientContext() client.Context {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.clientCtx.WithGRPCClient(p.nodes[p.active].Conn)
+}
+
+// ActiveNode returns the URL of the active node.
+func (p *NodePool) ActiveNode() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.nodes[p.active].URL
+}
+
+// ReportError is called when the request sent using the client context fails. If the error means that the node
+// is unavailable, the node is marked as unhealthy and another healthy node becomes active.
+func (p *NodePool) ReportError(ctx context.Context, clientCtx client.Context, err error) {
+	if !isUnavailableError(err) {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, node := range p.nodes {
+		if node.Conn != clientCtx.GRPCClient() {
+			continue
+		}
+		p.healthy[i] = false
+		p.health[i].err = err
+		if i == p.active {
+			p.failover(ctx)
+		}
+		return
+	}
+}
+
+// Statuses returns the state of all the nodes.
+func (p *NodePool) Statuses() []NodeStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make([]NodeStatus, 0, len(p.nodes))
+	for i, node := range p.nodes {
+		st := NodeStatus{
+			URL:     node.URL,
+			Active:  i == p.active,
+			Healthy: p.healthy[i],
+			Syncing: p.health[i].syncing,
+			Height:  p.health[i].height,
+		}
+		if p.health[i].err != nil {
+			st.Error = p.health[i].err.Error()
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
+
+// Run runs periodic health checks of the nodes.
+func (p *NodePool) Run(ctx context.Context) error {
+	for {
+		p.checkNodes(ctx)
+
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-time.After(p.healthCheckInterval):
+		}
+	}
+}
+
+func (p *NodePool) checkNodes(ctx context.Context) {
+	health := make([]nodeHealth, 0, len(p.nodes))
+	for _, node := range p.nodes {
+		health = append(health, p.checkNode(ctx, node))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.health = health
+	p.healthy = evaluateHealth(health, p.maxBlockLag)
+	if !p.healthy[p.active] {
+		p.failover(ctx)
+	}
+}
+
+func (p *NodePool) checkNode(ctx context.Context, node Node) nodeHealth {
+	ctx, cancel := context.WithTimeout(ctx, nodeRequestTimeout)
+	defer cancel()
+
+	tmQueryClient := cmtservice.NewServiceClient(p.clientCtx.WithGRPCClient(node.Conn))
+	syncingRes, err := tmQueryClient.GetSyncing(ctx, &cmtservice.GetSyncingRequest{})
+	if err != nil {
+		return nodeHealth{err: errors.WithStack(err)}
+	}
+	blockRes, err := tmQueryClient.GetLatestBlock(ctx, &cmtservice.GetLatestBlockRequest{})
+	if err != nil {
+		return nodeHealth{err: errors.WithStack(err)}
+	}
+
+	var height int64
+	if blockRes.SdkBlock != nil {
+		height = blockRes.SdkBlock.Header.Height
+	} else if blockRes.Block != nil { //nolint:staticcheck // older nodes don't set sdk block
+		height = blockRes.Block.Header.Height //nolint:staticcheck
+	}
+
+	return nodeHealth{
+		reachable: true,
+		syncing:   syncingRes.Syncing,
+		height:    height,
+	}
+}
+
+// failover selects the first healthy node as the active one. If there is no healthy node, the active one is kept.
+// Must be called with the lock held.
+func (p *NodePool) failover(ctx context.Context) {
+	log := logger.Get(ctx)
+	for i, healthy := range p.healthy {
+		if !healthy || i == p.active {
+			continue
+		}
+		log.Warn("Switching active node",
+			zap.String("from", p.nodes[p.active].URL),
+			zap.String("to", p.nodes[i].URL))
+		p.active = i
+		return
+	}
+	log.Error("No healthy node available", zap.String("active", p.nodes[p.active].URL))
+}
+
+// evaluateHealth tells which nodes are healthy. Node is healthy if it is reachable, not syncing and not lagging
+// behind the highest node by more than maxBlockLag blocks.
+func evaluateHealth(health []nodeHealth, maxBlockLag int64) []bool {
+	var maxHeight int64
+	for _, h := range health {
+		if h.reachable && !h.syncing && h.height > maxHeight {
+			maxHeight = h.height
+		}
+	}
+
+	healthy := make([]bool, 0, len(health))
+	for _, h := range health {
+		healthy = append(healthy, h.reachable && !h.syncing && h.height+maxBlockLag >= maxHeight)
+	}
+	return healthy
+}
+
+func isUnavailableError(err error) bool {
+	st, ok := status.FromError(err)
+	return ok && st.Code() == codes.Unavailable
+}
+
+var (
+	nodeActiveDesc  = prometheus.NewDesc("node_active", "Tells if the node is the active one", []string{"node"}, nil)
+	nodeHealthyDesc = prometheus.NewDesc(
+		"node_healthy", "Tells if the node is healthy", []string{"node"}, nil)
+	nodeHeightDesc = prometheus.NewDesc(
+		"node_block_height", "Latest block height reported by the node", []string{"node"}, nil)
+)
+
+// Describe implements prometheus.Collector interface.
+func (p *NodePool) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nodeActiveDesc
+	ch <- nodeHealthyDesc
+	ch <- nodeHeightDesc
+}
+
+// Collect implements prometheus.Collector interface.
+func (p *NodePool) Collect(ch chan<- prometheus.Metric) {
+	for _, st := range p.Statuses() {
+		ch <- prometheus.MustNewConstMetric(nodeActiveDesc, prometheus.GaugeValue, boolToFloat(st.Active), st.URL)
+		ch <- prometheus.MustNewConstMetric(nodeHealthyDesc, prometheus.GaugeValue, boolToFloat(st.Healthy), st.URL)
+		ch <- prometheus.MustNewConstMetric(nodeHeightDesc, prometheus.GaugeValue, float64(st.Height), st.URL)
+	}
+}
+
+func boolToFloat(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
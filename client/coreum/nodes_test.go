@@ -0,0 +1,55 @@
+package coreum
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateHealth(t *testing.T) {
+	testCases := []struct {
+		name     string
+		health   []nodeHealth
+		expected []bool
+	}{
+		{
+			name: "all healthy",
+			health: []nodeHealth{
+				{reachable: true, height: 100},
+				{reachable: true, height: 98},
+			},
+			expected: []bool{true, true},
+		},
+		{
+			name: "unreachable",
+			health: []nodeHealth{
+				{err: errors.New("unavailable")},
+				{reachable: true, height: 100},
+			},
+			expected: []bool{false, true},
+		},
+		{
+			name: "syncing",
+			health: []nodeHealth{
+				{reachable: true, syncing: true, height: 200},
+				{reachable: true, height: 100},
+			},
+			expected: []bool{false, true},
+		},
+		{
+			name: "lagging",
+			health: []nodeHealth{
+				{reachable: true, height: 94},
+				{reachable: true, height: 100},
+			},
+			expected: []bool{false, true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, evaluateHealth(tc.health, 5))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package coreum
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+)
+
+// RebalancerConfig is the configuration of the Rebalancer.
+type RebalancerConfig struct {
+	// Denom is the denom the funding accounts are rebalanced in.
+	Denom string
+	// LowWaterMark is the balance below which the funding account is topped up.
+	LowWaterMark sdkmath.Int
+	// Target is the balance the funding account is topped up to. Donor never goes below it.
+	Target sdkmath.Int
+	// Donor is the funding account the tokens are taken from. If nil, the richest funding account is used.
+	Donor sdk.AccAddress
+	// Interval is how often the balances are checked.
+	Interval time.Duration
+	// DryRun only logs the transfers instead of broadcasting them.
+	DryRun bool
+}
+
+// balanceClient is the interface of the client used by the Rebalancer.
+type balanceClient interface {
+	coreumClient
+	Balance(ctx context.Context, address sdk.AccAddress, denom string) (sdk.Coin, error)
+}
+
+// fundingAccounts provides the addresses of the funding accounts, which might change while running.
+type fundingAccounts interface {
+	FundingAddresses() []sdk.AccAddress
+}
+
+// Rebalancer moves the tokens between the funding accounts, so they drain evenly.
+type Rebalancer struct {
+	client          balanceClient
+	fundingAccounts fundingAccounts
+	config          RebalancerConfig
+	transfers       prometheus.Counter
+	transferred     prometheus.Counter
+}
+
+// NewRebalancer returns new instance of the Rebalancer.
+func NewRebalancer(client balanceClient, fundingAccounts fundingAccounts, config RebalancerConfig) *Rebalancer {
+	return &Rebalancer{
+		client:          client,
+		fundingAccounts: fundingAccounts,
+		config:          config,
+		transfers: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rebalance_transfers_total",
+			Help: "Number of transfers topping up the funding accounts",
+		}),
+		transferred: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rebalance_transferred_amount_total",
+			Help: "Amount transferred between the funding accounts",
+		}),
+	}
+}
+
+// Run checks the balances periodically and tops up the funding accounts below the low-water mark.
+func (r *Rebalancer) Run(ctx context.Context) error {
+	log := logger.Get(ctx)
+	for {
+		if err := r.rebalance(ctx); err != nil && ctx.Err() == nil {
+			log.Error("Rebalancing funding accounts failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-time.After(r.config.Interval):
+		}
+	}
+}
+
+type accountBalance struct {
+	address sdk.AccAddress
+	amount  sdkmath.Int
+}
+
+// rebalance tops up the funding accounts below the low-water mark, starting from the poorest one.
+func (r *Rebalancer) rebalance(ctx context.Context) error {
+	fundingAddresses := r.fundingAccounts.FundingAddresses()
+	balances := make([]*accountBalance, 0, len(fundingAddresses))
+	for _, address := range fundingAddresses {
+		balance, err := r.client.Balance(ctx, address, r.config.Denom)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get balance of %s", address)
+		}
+		balances = append(balances, &accountBalance{address: address, amount: balance.Amount})
+	}
+	sort.SliceStable(balances, func(i, j int) bool {
+		return balances[i].amount.LT(balances[j].amount)
+	})
+
+	for _, recipient := range balances {
+		if recipient.amount.GTE(r.config.LowWaterMark) {
+			break
+		}
+		donor := r.donor(balances, recipient)
+		if donor == nil {
+			logger.Get(ctx).Warn("No donor available to top up the funding account",
+				zap.Stringer("address", recipient.address),
+				zap.Stringer("balance", recipient.amount))
+			continue
+		}
+
+		// donor is never drained below the target, so the tokens don't move back and forth
+		amount := sdkmath.MinInt(r.config.Target.Sub(recipient.amount), donor.amount.Sub(r.config.Target))
+		if err := r.transfer(ctx, donor.address, recipient.address, amount); err != nil {
+			return err
+		}
+		donor.amount = donor.amount.Sub(amount)
+		recipient.amount = recipient.amount.Add(amount)
+	}
+	return nil
+}
+
+// donor returns the account the recipient is topped up from, nil is returned if there is no account having
+// funds above the target.
+func (r *Rebalancer) donor(balances []*accountBalance, recipient *accountBalance) *accountBalance {
+	var donor *accountBalance
+	for _, b := range balances {
+		if b == recipient {
+			continue
+		}
+		if r.config.Donor != nil {
+			if b.address.Equals(r.config.Donor) {
+				donor = b
+				break
+			}
+			continue
+		}
+		if donor == nil || b.amount.GT(donor.amount) {
+			donor = b
+		}
+	}
+	if donor == nil || donor.amount.LTE(r.config.Target) {
+		return nil
+	}
+	return donor
+}
+
+func (r *Rebalancer) transfer(ctx context.Context, from, to sdk.AccAddress, amount sdkmath.Int) error {
+	log := logger.Get(ctx).With(
+		zap.Stringer("from", from),
+		zap.Stringer("to", to),
+		zap.Stringer("amount", amount),
+		zap.String("denom", r.config.Denom))
+	if r.config.DryRun {
+		log.Info("Topping up funding account (dry run)")
+		return nil
+	}
+
+	log.Info("Topping up funding account")
+	ctx, cancel := context.WithTimeout(ctx, batchRequestTimeout)
+	defer cancel()
+
+	txHash, err := r.client.TransferToken(ctx, from, transferRequest{
+		destAddress: to,
+		amount:      sdk.NewCoins(sdk.NewCoin(r.config.Denom, amount)),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to top up %s from %s", to, from)
+	}
+	if err := r.client.AwaitTx(ctx, txHash); err != nil {
+		return errors.Wrapf(err, "top up of %s from %s not committed", to, from)
+	}
+
+	r.transfers.Inc()
+	r.transferred.Add(float64(amount.Uint64()))
+	return nil
+}
+
+// Describe implements prometheus.Collector interface.
+func (r *Rebalancer) Describe(ch chan<- *prometheus.Desc) {
+	r.transfers.Describe(ch)
+	r.transferred.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface.
+func (r *Rebalancer) Collect(ch chan<- prometheus.Metric) {
+	r.transfers.Collect(ch)
+	r.transferred.Collect(ch)
+}
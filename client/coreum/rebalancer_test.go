@@ -0,0 +1,108 @@
+package coreum
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+)
+
+type mockBalanceClient struct {
+	mockCoreumClient
+	balances map[string]int64
+}
+
+func (mc *mockBalanceClient) Balance(_ context.Context, address sdk.AccAddress, denom string) (sdk.Coin, error) {
+	return sdk.NewInt64Coin(denom, mc.balances[address.String()]), nil
+}
+
+// staticFundingAccounts is the fixed set of the funding accounts.
+type staticFundingAccounts []sdk.AccAddress
+
+func (s staticFundingAccounts) FundingAddresses() []sdk.AccAddress {
+	return s
+}
+
+func TestRebalance(t *testing.T) {
+	poor, rich, medium := newAddress(), newAddress(), newAddress()
+
+	type transfer struct {
+		from   sdk.AccAddress
+		to     sdk.AccAddress
+		amount int64
+	}
+	tests := []struct {
+		name     string
+		balances []int64
+		donor    sdk.AccAddress
+		dryRun   bool
+		expected []transfer
+	}{
+		{
+			name:     "richest_donor",
+			balances: []int64{100, 1000, 500},
+			expected: []transfer{{from: rich, to: poor, amount: 200}},
+		},
+		{
+			name:     "designated_donor",
+			balances: []int64{100, 1000, 500},
+			donor:    medium,
+			expected: []transfer{{from: medium, to: poor, amount: 200}},
+		},
+		{
+			name:     "donor_kept_above_target",
+			balances: []int64{100, 350, 250},
+			expected: []transfer{{from: rich, to: poor, amount: 50}},
+		},
+		{
+			name:     "no_donor",
+			balances: []int64{100, 300, 250},
+		},
+		{
+			name:     "balanced",
+			balances: []int64{200, 1000, 500},
+		},
+		{
+			name:     "dry_run",
+			balances: []int64{100, 1000, 500},
+			dryRun:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireT := require.New(t)
+			ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+
+			addresses := []sdk.AccAddress{poor, rich, medium}
+			mock := &mockBalanceClient{balances: map[string]int64{}}
+			for i, address := range addresses {
+				mock.balances[address.String()] = tt.balances[i]
+			}
+			rebalancer := NewRebalancer(mock, staticFundingAccounts(addresses), RebalancerConfig{
+				Denom:        "test-denom",
+				LowWaterMark: sdkmath.NewInt(200),
+				Target:       sdkmath.NewInt(300),
+				Donor:        tt.donor,
+				Interval:     time.Minute,
+				DryRun:       tt.dryRun,
+			})
+			requireT.NoError(rebalancer.rebalance(ctx))
+
+			requireT.Len(mock.calls, len(tt.expected))
+			for i, expected := range tt.expected {
+				call := mock.calls[i]
+				requireT.Equal(expected.from, call.fromAddress)
+				requireT.Len(call.requests, 1)
+				requireT.Equal(expected.to, call.requests[0].destAddress)
+				requireT.Equal(sdk.NewCoins(sdk.NewInt64Coin("test-denom", expected.amount)), call.requests[0].amount)
+			}
+		})
+	}
+}
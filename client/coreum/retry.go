@@ -0,0 +1,104 @@
+package coreum
+
+import (
+	"context"
+	"strings"
+
+	sdkerrors "cosmossdk.io/errors"
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rejectedErrors are the errors proving the tx has been rejected before it was added to the mempool, which are likely
+// to disappear if the tx is broadcast again.
+var rejectedErrors = []*sdkerrors.Error{
+	cosmoserrors.ErrWrongSequence,
+	cosmoserrors.ErrMempoolIsFull,
+}
+
+// notBroadcastError is returned if the tx failed before it was sent to the node.
+type notBroadcastError struct {
+	error
+}
+
+func (e notBroadcastError) Unwrap() error {
+	return e.error
+}
+
+// isNotBroadcastError tells if the tx failed before it was sent to the node, so it is safe to send it again
+// from any funding account.
+func isNotBroadcastError(err error) bool {
+	var notBroadcastErr notBroadcastError
+	return errors.As(err, &notBroadcastErr)
+}
+
+// isTransientError tells if broadcasting the tx failed for the reason not related to its content, while it is known
+// that the tx hasn't been accepted by the node, so the same tx might be broadcast again.
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if isNotBroadcastError(err) {
+		return isTransportError(err)
+	}
+	return isRejectedError(err)
+}
+
+// isAmbiguousError tells if the tx has been sent to the node but it is unknown whether the node accepted it.
+// Such tx must not be sent from another funding account, as both of them might be committed.
+func isAmbiguousError(err error) bool {
+	return err != nil && !isNotBroadcastError(err) && !isRejectedError(err) && isTransportError(err)
+}
+
+func isRejectedError(err error) bool {
+	for _, rejectedErr := range rejectedErrors {
+		if errorMatches(err, rejectedErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransportError tells if the error is caused by the connection to the node rather than returned by the node.
+func isTransportError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	// ABCI errors returned by the node implement gRPC status too
+	var abciErr *sdkerrors.Error
+	if errors.As(err, &abciErr) {
+		return false
+	}
+	if st, ok := status.FromError(errors.Cause(err)); ok {
+		switch st.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.Unknown, codes.Internal,
+			codes.Aborted, codes.ResourceExhausted:
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// isRequestError tells if the tx has been rejected because of one of the transfer requests, not because of
+// the funding account sending it, so the batch should be bisected to find the offending requests.
+func isRequestError(err error) bool {
+	switch {
+	case errorMatches(err, cosmoserrors.ErrInvalidAddress),
+		errorMatches(err, cosmoserrors.ErrInvalidCoins):
+		return true
+	// ErrUnauthorized is also returned if the signature is invalid, so only the blocked recipient is matched
+	case errorMatches(err, cosmoserrors.ErrUnauthorized):
+		return strings.Contains(err.Error(), "not allowed to receive funds")
+	default:
+		return false
+	}
+}
+
+// isExecutionError tells if the committed tx has been executed with an error code.
+func isExecutionError(err error) bool {
+	var abciErr *sdkerrors.Error
+	return errors.As(err, &abciErr)
+}
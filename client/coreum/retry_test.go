@@ -0,0 +1,52 @@
+package coreum
+
+import (
+	"context"
+	"testing"
+
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsTransientError(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.True(isTransientError(errors.Wrap(cosmoserrors.ErrWrongSequence, "account sequence mismatch")))
+	assertT.True(isTransientError(errors.New("rpc error: code = Unknown desc = mempool is full")))
+	assertT.True(isTransientError(notBroadcastError{error: errors.WithStack(context.DeadlineExceeded)}))
+	assertT.True(isTransientError(notBroadcastError{error: status.Error(codes.Unavailable, "connection refused")}))
+
+	assertT.False(isTransientError(nil))
+	assertT.False(isTransientError(errors.WithStack(context.DeadlineExceeded)))
+	assertT.False(isTransientError(status.Error(codes.Unavailable, "connection refused")))
+	assertT.False(isTransientError(errors.Wrap(cosmoserrors.ErrInsufficientFunds, "spendable balance is too low")))
+	assertT.False(isTransientError(status.Error(codes.InvalidArgument, "invalid address")))
+}
+
+func TestIsAmbiguousError(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.True(isAmbiguousError(errors.WithStack(context.DeadlineExceeded)))
+	assertT.True(isAmbiguousError(status.Error(codes.Unavailable, "connection refused")))
+	assertT.True(isAmbiguousError(status.Error(codes.DeadlineExceeded, "timeout")))
+
+	assertT.False(isAmbiguousError(nil))
+	assertT.False(isAmbiguousError(notBroadcastError{error: status.Error(codes.Unavailable, "connection refused")}))
+	assertT.False(isAmbiguousError(errors.Wrap(cosmoserrors.ErrInsufficientFunds, "spendable balance is too low")))
+	assertT.False(isAmbiguousError(errors.New("rpc error: code = Unknown desc = mempool is full")))
+}
+
+func TestIsRequestError(t *testing.T) {
+	assertT := assert.New(t)
+
+	assertT.True(isRequestError(errors.Wrap(cosmoserrors.ErrInvalidAddress, "invalid recipient")))
+	assertT.True(isRequestError(errors.Wrapf(cosmoserrors.ErrUnauthorized,
+		"%s is not allowed to receive funds", "cosmos1abc")))
+
+	assertT.False(isRequestError(errors.Wrap(cosmoserrors.ErrUnauthorized, "signature verification failed")))
+	assertT.False(isRequestError(errors.Wrap(cosmoserrors.ErrInsufficientFee, "insufficient fee")))
+	assertT.False(isRequestError(errors.Wrap(cosmoserrors.ErrUnknownAddress, "account not found")))
+}
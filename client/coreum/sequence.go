@@ -0,0 +1,80 @@
+package coreum
+
+import (
+	"regexp"
+	"strconv"
+	"sync"
+
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+// sequenceMismatchRegexp extracts the sequence expected by the node from the sequence mismatch error.
+var sequenceMismatchRegexp = regexp.MustCompile(`account sequence mismatch, expected (\d+)`)
+
+type accountSequence struct {
+	mu            sync.Mutex
+	known         bool
+	accountNumber uint64
+	sequence      uint64
+}
+
+// sequenceCache stores account numbers and sequences of the funding accounts, so they don't need to be queried
+// before each tx is broadcast.
+type sequenceCache struct {
+	mu       sync.Mutex
+	accounts map[string]*accountSequence
+}
+
+func newSequenceCache() *sequenceCache {
+	return &sequenceCache{
+		accounts: map[string]*accountSequence{},
+	}
+}
+
+// account returns the sequence of the account. Its lock must be held while the tx is broadcast from the account.
+func (c *sequenceCache) account(address string) *accountSequence {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	acc, ok := c.accounts[address]
+	if !ok {
+		acc = &accountSequence{}
+		c.accounts[address] = acc
+	}
+	return acc
+}
+
+// forget drops the sequence of the account which is no longer used. It is queried again if the account is used
+// later.
+func (c *sequenceCache) forget(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.accounts, address)
+}
+
+// reportError updates the cached sequence after the failed broadcast. The sequence expected by the node is taken
+// from the sequence mismatch error, if possible. If it is unknown whether the tx was accepted, the sequence is kept,
+// so the same tx is broadcast again instead of a new one. Must be called with the lock held.
+func (s *accountSequence) reportError(err error) {
+	if !errorMatches(err, cosmoserrors.ErrWrongSequence) {
+		return
+	}
+	if sequence, ok := expectedSequence(err); ok {
+		s.sequence = sequence
+		return
+	}
+	s.known = false
+}
+
+func expectedSequence(err error) (uint64, bool) {
+	matches := sequenceMismatchRegexp.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return 0, false
+	}
+	sequence, parseErr := strconv.ParseUint(matches[1], 10, 64)
+	if parseErr != nil {
+		return 0, false
+	}
+	return sequence, true
+}
@@ -0,0 +1,38 @@
+package coreum
+
+import (
+	"testing"
+
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAccountSequenceReportError(t *testing.T) {
+	requireT := require.New(t)
+
+	acc := &accountSequence{known: true, accountNumber: 3, sequence: 5}
+	acc.reportError(errors.New("rpc error: code = Unknown desc = account sequence mismatch, expected 8, " +
+		"got 5: incorrect account sequence"))
+	requireT.True(acc.known)
+	requireT.EqualValues(8, acc.sequence)
+
+	acc.reportError(errors.Wrap(cosmoserrors.ErrUnauthorized, "blocked address"))
+	requireT.True(acc.known)
+	requireT.EqualValues(8, acc.sequence)
+
+	acc.reportError(errors.Wrap(cosmoserrors.ErrWrongSequence, "unexpected format"))
+	requireT.False(acc.known)
+
+	acc = &accountSequence{known: true, accountNumber: 3, sequence: 5}
+	acc.reportError(errors.Wrap(cosmoserrors.ErrMempoolIsFull, "try later"))
+	requireT.True(acc.known)
+	requireT.EqualValues(5, acc.sequence)
+
+	// it is unknown whether the tx was accepted, so the same sequence is used again
+	acc.reportError(status.Error(codes.Unavailable, "connection refused"))
+	requireT.True(acc.known)
+	requireT.EqualValues(5, acc.sequence)
+}
@@ -0,0 +1,293 @@
+package coreum
+
+import (
+	"context"
+	"time"
+
+	sdkerrors "cosmossdk.io/errors"
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+)
+
+// TreasuryConfig is the configuration of the Treasury.
+type TreasuryConfig struct {
+	// Denom is the denom the funding accounts are refilled in.
+	Denom string
+	// Address is the address of the treasury account, it is never used to fund the requests.
+	Address sdk.AccAddress
+	// Threshold is the total balance of the funding accounts below which they are refilled.
+	Threshold sdkmath.Int
+	// Target is the total balance of the funding accounts they are refilled to.
+	Target sdkmath.Int
+	// DailyBudget is the maximum amount sent from the treasury during the UTC day.
+	DailyBudget sdkmath.Int
+	// Interval is how often the balances are checked.
+	Interval time.Duration
+}
+
+// refillLedger records the refills, so the daily budget is respected across restarts.
+type refillLedger interface {
+	CreateRefill(refill ledger.Refill) (ledger.Refill, error)
+	UpdateRefill(id string, update func(refill *ledger.Refill)) (ledger.Refill, error)
+	Refills(since time.Time) ([]ledger.Refill, error)
+}
+
+// Treasury refills the funding accounts from the treasury account once their total balance drops below
+// the threshold.
+type Treasury struct {
+	client          balanceClient
+	ledger          refillLedger
+	fundingAccounts fundingAccounts
+	config          TreasuryConfig
+	refills         prometheus.Counter
+	refilled        prometheus.Counter
+	budgetRemaining prometheus.Gauge
+}
+
+// NewTreasury returns new instance of the Treasury.
+func NewTreasury(
+	client balanceClient,
+	ledger refillLedger,
+	fundingAccounts fundingAccounts,
+	config TreasuryConfig,
+) *Treasury {
+	return &Treasury{
+		client:          client,
+		ledger:          ledger,
+		fundingAccounts: fundingAccounts,
+		config:          config,
+		refills: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "treasury_refills_total",
+			Help: "Number of transfers refilling the funding accounts from the treasury",
+		}),
+		refilled: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "treasury_refilled_amount_total",
+			Help: "Amount sent from the treasury to the funding accounts",
+		}),
+		budgetRemaining: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "treasury_budget_remaining",
+			Help: "Amount which might still be sent from the treasury during the current UTC day",
+		}),
+	}
+}
+
+// Run checks the balances periodically and refills the funding accounts if needed.
+func (t *Treasury) Run(ctx context.Context) error {
+	log := logger.Get(ctx)
+	for {
+		if err := t.refill(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			log.Error("Refilling funding accounts from treasury failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-time.After(t.config.Interval):
+		}
+	}
+}
+
+// refill refills the funding accounts up to the target if their total balance is below the threshold, within
+// the budget remaining for the day.
+func (t *Treasury) refill(ctx context.Context, now time.Time) error {
+	remaining, err := t.remainingBudget(now)
+	if err != nil {
+		return err
+	}
+	t.budgetRemaining.Set(float64(remaining.Uint64()))
+
+	fundingAddresses := t.fundingAccounts.FundingAddresses()
+	balances := make([]sdkmath.Int, 0, len(fundingAddresses))
+	total := sdkmath.ZeroInt()
+	for _, address := range fundingAddresses {
+		balance, err := t.client.Balance(ctx, address, t.config.Denom)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get balance of %s", address)
+		}
+		balances = append(balances, balance.Amount)
+		total = total.Add(balance.Amount)
+	}
+	if total.GTE(t.config.Threshold) {
+		return nil
+	}
+
+	log := logger.Get(ctx)
+	amount := sdkmath.MinInt(t.config.Target.Sub(total), remaining)
+	treasuryBalance, err := t.client.Balance(ctx, t.config.Address, t.config.Denom)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get balance of treasury %s", t.config.Address)
+	}
+	amount = sdkmath.MinInt(amount, treasuryBalance.Amount)
+	if !amount.IsPositive() {
+		log.Warn("Funding accounts need refill but treasury budget or balance is exhausted",
+			zap.Stringer("total", total),
+			zap.Stringer("budgetRemaining", remaining),
+			zap.Stringer("treasuryBalance", treasuryBalance.Amount))
+		return nil
+	}
+
+	requests := t.distribute(fundingAddresses, balances, amount)
+	recipients := make([]string, 0, len(requests))
+	for _, rq := range requests {
+		recipients = append(recipients, rq.destAddress.String())
+	}
+	log.Info("Refilling funding accounts from treasury",
+		zap.Stringer("treasury", t.config.Address),
+		zap.Stringer("total", total),
+		zap.Stringer("amount", amount),
+		zap.Strings("recipients", recipients))
+
+	// refill is recorded before it is broadcast, so it counts toward the budget even if the faucet is stopped
+	// before its outcome is known
+	refill, err := t.ledger.CreateRefill(ledger.Refill{
+		Status:     ledger.StatusQueued,
+		Treasury:   t.config.Address.String(),
+		Recipients: recipients,
+		Amount:     amount.String(),
+		Denom:      t.config.Denom,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to record the refill in the ledger")
+	}
+
+	txCtx, cancel := context.WithTimeout(ctx, batchRequestTimeout)
+	defer cancel()
+	txHash, err := t.client.TransferToken(txCtx, t.config.Address, requests...)
+	if err != nil {
+		// refill which might have been accepted by the node still counts toward the budget
+		status := ledger.StatusFailed
+		if isAmbiguousError(err) {
+			status = ledger.StatusUnknown
+		}
+		t.updateRefill(ctx, refill.ID, txHash, status, err)
+		return errors.Wrap(err, "unable to refill funding accounts")
+	}
+	t.updateRefill(ctx, refill.ID, txHash, ledger.StatusBroadcast, nil)
+	t.refills.Inc()
+	t.refilled.Add(float64(amount.Uint64()))
+	t.budgetRemaining.Set(float64(remaining.Sub(amount).Uint64()))
+
+	if err := t.client.AwaitTx(txCtx, txHash); err != nil {
+		// only the tx executed with an error code is known to be failed, the one not found might still be committed
+		status := ledger.StatusUnknown
+		var abciErr *sdkerrors.Error
+		if errors.As(err, &abciErr) {
+			status = ledger.StatusFailed
+		}
+		t.updateRefill(ctx, refill.ID, txHash, status, err)
+		return errors.Wrapf(err, "refill %s not committed", txHash)
+	}
+	t.updateRefill(ctx, refill.ID, txHash, ledger.StatusCommitted, nil)
+	return nil
+}
+
+// updateRefill records the outcome of the refill. Refill which can't be updated keeps counting toward the budget,
+// so the error is only logged.
+func (t *Treasury) updateRefill(ctx context.Context, id, txHash string, status ledger.Status, refillErr error) {
+	_, err := t.ledger.UpdateRefill(id, func(refill *ledger.Refill) {
+		refill.TxHash = txHash
+		refill.Status = status
+		if refillErr != nil {
+			refill.Error = refillErr.Error()
+		}
+	})
+	if err != nil {
+		logger.Get(ctx).Error("Unable to update the refill in the ledger",
+			zap.String("id", id),
+			zap.String("txHash", txHash),
+			zap.Error(err))
+	}
+}
+
+// remainingBudget returns the amount which might still be sent during the UTC day. All the refills except the ones
+// known to be failed count toward the budget.
+func (t *Treasury) remainingBudget(now time.Time) (sdkmath.Int, error) {
+	dayStart := now.Truncate(24 * time.Hour)
+	refills, err := t.ledger.Refills(dayStart)
+	if err != nil {
+		return sdkmath.Int{}, errors.Wrap(err, "unable to get refills")
+	}
+	used := sdkmath.ZeroInt()
+	for _, refill := range refills {
+		if refill.Status == ledger.StatusFailed {
+			continue
+		}
+		amount, ok := sdkmath.NewIntFromString(refill.Amount)
+		if !ok {
+			return sdkmath.Int{}, errors.Errorf("invalid amount %q of refill %s", refill.Amount, refill.ID)
+		}
+		used = used.Add(amount)
+	}
+	return sdkmath.MaxInt(t.config.DailyBudget.Sub(used), sdkmath.ZeroInt()), nil
+}
+
+// distribute splits the amount between the funding accounts proportionally to how far they are below their share
+// of the target.
+func (t *Treasury) distribute(
+	fundingAddresses []sdk.AccAddress,
+	balances []sdkmath.Int,
+	amount sdkmath.Int,
+) []transferRequest {
+	share := t.config.Target.QuoRaw(int64(len(balances)))
+	deficits := make([]sdkmath.Int, 0, len(balances))
+	totalDeficit := sdkmath.ZeroInt()
+	for _, balance := range balances {
+		deficit := sdkmath.MaxInt(share.Sub(balance), sdkmath.ZeroInt())
+		deficits = append(deficits, deficit)
+		totalDeficit = totalDeficit.Add(deficit)
+	}
+
+	// the accounts are within the rounding error of their shares, so everything goes to the poorest one
+	if totalDeficit.IsZero() {
+		poorest := 0
+		for i, balance := range balances {
+			if balance.LT(balances[poorest]) {
+				poorest = i
+			}
+		}
+		deficits[poorest], totalDeficit = amount, amount
+	}
+
+	var requests []transferRequest
+	left, remainingDeficit := amount, totalDeficit
+	for i, deficit := range deficits {
+		if !deficit.IsPositive() {
+			continue
+		}
+		part := amount.Mul(deficit).Quo(totalDeficit)
+		remainingDeficit = remainingDeficit.Sub(deficit)
+		// the last account gets the remainder of the rounding
+		if remainingDeficit.IsZero() {
+			part = left
+		}
+		if !part.IsPositive() {
+			continue
+		}
+		left = left.Sub(part)
+		requests = append(requests, transferRequest{
+			destAddress: fundingAddresses[i],
+			amount:      sdk.NewCoins(sdk.NewCoin(t.config.Denom, part)),
+		})
+	}
+	return requests
+}
+
+// Describe implements prometheus.Collector interface.
+func (t *Treasury) Describe(ch chan<- *prometheus.Desc) {
+	t.refills.Describe(ch)
+	t.refilled.Describe(ch)
+	t.budgetRemaining.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface.
+func (t *Treasury) Collect(ch chan<- prometheus.Metric) {
+	t.refills.Collect(ch)
+	t.refilled.Collect(ch)
+	t.budgetRemaining.Collect(ch)
+}
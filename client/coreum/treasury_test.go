@@ -0,0 +1,170 @@
+package coreum
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	cosmoserrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+)
+
+func TestTreasuryRefill(t *testing.T) {
+	requireT := require.New(t)
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+
+	store, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.db"))
+	requireT.NoError(err)
+	t.Cleanup(func() {
+		requireT.NoError(store.Close())
+	})
+
+	treasury, first, second := newAddress(), newAddress(), newAddress()
+	mock := &mockBalanceClient{balances: map[string]int64{
+		treasury.String(): 10000,
+		first.String():    100,
+		second.String():   400,
+	}}
+	tr := NewTreasury(mock, store, staticFundingAccounts{first, second}, TreasuryConfig{
+		Denom:       "test-denom",
+		Address:     treasury,
+		Threshold:   sdkmath.NewInt(600),
+		Target:      sdkmath.NewInt(1000),
+		DailyBudget: sdkmath.NewInt(700),
+		Interval:    time.Minute,
+	})
+
+	// total balance 500 is refilled to the target, proportionally to the shares missing
+	now := time.Now().UTC()
+	requireT.NoError(tr.refill(ctx, now))
+	requireT.Len(mock.calls, 1)
+	call := mock.calls[0]
+	requireT.Equal(treasury, call.fromAddress)
+	requireT.Len(call.requests, 2)
+	requireT.Equal(first, call.requests[0].destAddress)
+	requireT.Equal(sdk.NewCoins(sdk.NewInt64Coin("test-denom", 400)), call.requests[0].amount)
+	requireT.Equal(second, call.requests[1].destAddress)
+	requireT.Equal(sdk.NewCoins(sdk.NewInt64Coin("test-denom", 100)), call.requests[1].amount)
+
+	refills, err := store.Refills(time.Time{})
+	requireT.NoError(err)
+	requireT.Len(refills, 1)
+	requireT.Equal("500", refills[0].Amount)
+	requireT.Equal(ledger.StatusCommitted, refills[0].Status)
+	requireT.Equal(treasury.String(), refills[0].TxHash)
+
+	// total balance above the threshold is not refilled
+	mock.balances[first.String()] = 500
+	mock.balances[second.String()] = 500
+	requireT.NoError(tr.refill(ctx, now))
+	requireT.Len(mock.calls, 1)
+
+	// refill is capped by the remaining daily budget
+	mock.balances[first.String()] = 0
+	mock.balances[second.String()] = 0
+	requireT.NoError(tr.refill(ctx, now))
+	requireT.Len(mock.calls, 2)
+	sent := sdkmath.ZeroInt()
+	for _, rq := range mock.calls[1].requests {
+		sent = sent.Add(rq.amount.AmountOf("test-denom"))
+	}
+	requireT.Equal(sdkmath.NewInt(200), sent)
+
+	// budget is exhausted for the day
+	requireT.NoError(tr.refill(ctx, now))
+	requireT.Len(mock.calls, 2)
+
+	// budget is renewed on the next day
+	requireT.NoError(tr.refill(ctx, now.Add(24*time.Hour)))
+	requireT.Len(mock.calls, 3)
+}
+
+func TestTreasuryRefillBudgetCountsUnfinishedRefills(t *testing.T) {
+	requireT := require.New(t)
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+
+	store, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.db"))
+	requireT.NoError(err)
+	t.Cleanup(func() {
+		requireT.NoError(store.Close())
+	})
+
+	treasury, first := newAddress(), newAddress()
+	mock := &mockBalanceClient{balances: map[string]int64{
+		treasury.String(): 10000,
+	}}
+	refillLedger := &failingRefillLedger{Store: store}
+	tr := NewTreasury(mock, refillLedger, staticFundingAccounts{first}, TreasuryConfig{
+		Denom:       "test-denom",
+		Address:     treasury,
+		Threshold:   sdkmath.NewInt(100),
+		Target:      sdkmath.NewInt(100),
+		DailyBudget: sdkmath.NewInt(250),
+		Interval:    time.Minute,
+	})
+	now := time.Now().UTC()
+
+	// refill which can't be recorded is not broadcast
+	refillLedger.fail = true
+	requireT.Error(tr.refill(ctx, now))
+	requireT.Empty(mock.calls)
+	refillLedger.fail = false
+
+	// refill rejected by the node doesn't count toward the budget
+	mock.fail = func(sdk.AccAddress, []transferRequest) error {
+		return cosmoserrors.ErrInsufficientFunds
+	}
+	requireT.Error(tr.refill(ctx, now))
+	requireT.Len(mock.calls, 1)
+
+	// refill which might have been accepted by the node counts toward the budget
+	mock.fail = func(sdk.AccAddress, []transferRequest) error {
+		return status.Error(codes.Unavailable, "connection lost")
+	}
+	requireT.Error(tr.refill(ctx, now))
+	requireT.Len(mock.calls, 2)
+
+	// refill not found on chain counts toward the budget
+	mock.fail = nil
+	mock.await = func(context.Context, string) error {
+		return errors.New("transaction hasn't been included in a block yet")
+	}
+	requireT.Error(tr.refill(ctx, now))
+	requireT.Len(mock.calls, 3)
+
+	refills, err := store.Refills(time.Time{})
+	requireT.NoError(err)
+	requireT.Len(refills, 3)
+	requireT.Equal(ledger.StatusFailed, refills[0].Status)
+	requireT.Equal(ledger.StatusUnknown, refills[1].Status)
+	requireT.Equal(ledger.StatusUnknown, refills[2].Status)
+
+	// only 50 is left in the budget
+	mock.await = nil
+	requireT.NoError(tr.refill(ctx, now))
+	requireT.Len(mock.calls, 4)
+	requireT.Equal(sdk.NewCoins(sdk.NewInt64Coin("test-denom", 50)), mock.calls[3].requests[0].amount)
+}
+
+// failingRefillLedger fails to create the refill if requested.
+type failingRefillLedger struct {
+	*ledger.Store
+	fail bool
+}
+
+func (l *failingRefillLedger) CreateRefill(refill ledger.Refill) (ledger.Refill, error) {
+	if l.fail {
+		return ledger.Refill{}, errors.New("disk is full")
+	}
+	return l.Store.CreateRefill(refill)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+
+	"github.com/CoreumFoundation/faucet/app"
+	"github.com/CoreumFoundation/faucet/http"
+	"github.com/CoreumFoundation/faucet/pkg/config"
+	"github.com/CoreumFoundation/faucet/pkg/limiter"
+	"github.com/CoreumFoundation/faucet/pkg/logger"
+	"github.com/CoreumFoundation/faucet/pkg/signal"
+)
+
+const (
+	cmdConfig      = "config"
+	cmdConfigPrint = "print"
+
+	flagVerbose = "verbose"
+)
+
+// secretFlags are the flags which values are redacted when the config is printed or reloaded.
+var secretFlags = []string{flagAPIKeys, flagInternalAPIKeys}
+
+// reloadableFlags are the flags applied on SIGHUP, changing any other one requires restart.
+var reloadableFlags = []string{
+	flagTransferAmount,
+	flagTransferCoins,
+	flagMaxTransferCoins,
+	flagIPRateLimit,
+	flagSubnetRateLimit,
+	flagAddressRateLimit,
+	flagAPIKeys,
+	flagInternalAPIKeys,
+	flagVerbose,
+}
+
+// printConfigCmd prints the effective config merged from the config file, env vars and flags, so it might be
+// verified before the faucet is started or used as the config file.
+func printConfigCmd(args []string) {
+	_, log, opts, cfg := setup(args)
+	if cfg.help {
+		return
+	}
+	if err := config.Print(os.Stdout, opts.flagSet, secretFlags, []string{"help", flagConfig}); err != nil {
+		log.Fatal("Error printing config", zap.Error(err))
+	}
+}
+
+// configReloader re-reads the config on SIGHUP and applies the changes of the reloadable flags.
+type configReloader struct {
+	args           []string
+	denom          string
+	application    app.App
+	ipLimiter      *limiter.WeightedWindowLimiter
+	subnetLimiter  *limiter.WeightedWindowLimiter
+	addressLimiter *limiter.WeightedWindowLimiter
+	apiKeys        *http.APIKeys
+	level          zap.AtomicLevel
+	// applied are the values of the flags the faucet currently runs with.
+	applied map[string]string
+}
+
+func newConfigReloader(
+	args []string,
+	opts options,
+	denom string,
+	application app.App,
+	ipLimiter *limiter.WeightedWindowLimiter,
+	subnetLimiter *limiter.WeightedWindowLimiter,
+	addressLimiter *limiter.WeightedWindowLimiter,
+	apiKeys *http.APIKeys,
+) *configReloader {
+	return &configReloader{
+		args:           args,
+		denom:          denom,
+		application:    application,
+		ipLimiter:      ipLimiter,
+		subnetLimiter:  subnetLimiter,
+		addressLimiter: addressLimiter,
+		apiKeys:        apiKeys,
+		level:          opts.level,
+		applied:        flagValues(opts.flagSet),
+	}
+}
+
+// Run reloads the config each time SIGHUP is received.
+func (r *configReloader) Run(ctx context.Context) error {
+	log := logger.Get(ctx)
+	reloadChan := signal.ReloadSignal(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-reloadChan:
+			if err := r.reload(ctx); err != nil {
+				log.Error("Reloading config failed, the current one is kept", zap.Error(err))
+			}
+		}
+	}
+}
+
+// reload reads the config the same way it is read on start and applies the reloadable flags. Changes of the other
+// flags are logged and ignored.
+func (r *configReloader) reload(ctx context.Context) error {
+	_, loggerFlagRegistry := logger.ConfigureWithCLI(logger.ServiceDefaultConfig)
+	flagSet := pflag.NewFlagSet("faucet", pflag.ContinueOnError)
+	loggerFlagRegistry(flagSet)
+	conf, err := parseConfig(flagSet, r.args)
+	if err != nil {
+		return err
+	}
+	loggerConfig, err := logger.ApplyFlags(logger.ServiceDefaultConfig, flagSet)
+	if err != nil {
+		return err
+	}
+
+	log := logger.Get(ctx)
+	values := flagValues(flagSet)
+	names := lo.Keys(values)
+	sort.Strings(names)
+	var changed, refused []string
+	for _, name := range names {
+		oldValue, newValue := r.applied[name], values[name]
+		if oldValue == newValue {
+			continue
+		}
+		if !lo.Contains(reloadableFlags, name) {
+			refused = append(refused, name)
+			continue
+		}
+		changed = append(changed, name)
+		if lo.Contains(secretFlags, name) {
+			log.Info("Config changed", zap.String("key", name))
+			continue
+		}
+		log.Info("Config changed", zap.String("key", name), zap.String("old", oldValue), zap.String("new", newValue))
+	}
+	if len(refused) > 0 {
+		log.Warn("Config changes requiring restart are ignored", zap.Strings("keys", refused))
+	}
+	if len(changed) == 0 {
+		log.Info("No config changes to apply")
+		return nil
+	}
+
+	transferAmount, maxTransferAmount := transferAmounts(conf, r.denom)
+	r.application.SetTransferAmounts(transferAmount, maxTransferAmount)
+	r.ipLimiter.SetLimit(conf.ipRateLimit.howMany, conf.ipRateLimit.period)
+	r.subnetLimiter.SetLimit(conf.subnetRateLimit.howMany, conf.subnetRateLimit.period)
+	r.addressLimiter.SetLimit(conf.addressRateLimit.howMany, conf.addressRateLimit.period)
+	r.apiKeys.Set(apiKeys(conf))
+	r.level.SetLevel(logger.Level(loggerConfig))
+	for _, name := range changed {
+		r.applied[name] = values[name]
+	}
+	log.Info("Config reloaded", zap.Strings("keys", changed))
+	return nil
+}
+
+// flagValues returns the values of the flags by their names.
+func flagValues(flagSet *pflag.FlagSet) map[string]string {
+	values := map[string]string{}
+	flagSet.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name != "help" {
+			values[flag.Name] = flag.Value.String()
+		}
+	})
+	return values
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config/constant"
+	"github.com/CoreumFoundation/faucet/app"
+	"github.com/CoreumFoundation/faucet/http"
+	"github.com/CoreumFoundation/faucet/pkg/limiter"
+	"github.com/CoreumFoundation/faucet/pkg/logger"
+)
+
+func TestConfigReloader(t *testing.T) {
+	requireT := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		requireT.NoError(os.WriteFile(path, []byte(content), 0o600))
+	}
+	writeConfig("address-rate-limit: 1/24h\napi-keys: secret1\nbatch-size: 10\n")
+
+	// config is read the same way it is read on start
+	args := []string{"--" + flagConfig + "=" + path}
+	_, loggerFlagRegistry := logger.ConfigureWithCLI(logger.ServiceDefaultConfig)
+	flagSet := pflag.NewFlagSet("faucet", pflag.ContinueOnError)
+	loggerFlagRegistry(flagSet)
+	conf, err := parseConfig(flagSet, args)
+	requireT.NoError(err)
+
+	network, err := config.NetworkConfigByChainID(constant.ChainIDDev)
+	requireT.NoError(err)
+	transferAmount, maxTransferAmount := transferAmounts(conf, network.Denom())
+	addressLimiter := limiter.NewWeightedWindowLimiter(conf.addressRateLimit.howMany, conf.addressRateLimit.period)
+	r := newConfigReloader(
+		args,
+		options{flagSet: flagSet, level: zap.NewAtomicLevel()},
+		network.Denom(),
+		app.New(client.Context{}, nil, nil, nil, network, transferAmount, maxTransferAmount, addressLimiter),
+		limiter.NewWeightedWindowLimiter(conf.ipRateLimit.howMany, conf.ipRateLimit.period),
+		limiter.NewWeightedWindowLimiter(conf.subnetRateLimit.howMany, conf.subnetRateLimit.period),
+		addressLimiter,
+		http.NewAPIKeys(apiKeys(conf)),
+	)
+
+	reload := func() *observer.ObservedLogs {
+		core, logs := observer.New(zap.InfoLevel)
+		requireT.NoError(r.reload(logger.WithLogger(t.Context(), zap.New(core))))
+		return logs
+	}
+
+	requireT.True(addressLimiter.Reserve("address"))
+	requireT.False(addressLimiter.Reserve("address"))
+
+	writeConfig("address-rate-limit: 2/24h\napi-keys: secret2\nbatch-size: 20\n")
+	logs := reload()
+
+	// diff of the reloadable flags is logged, values of the secrets are not
+	changed := logs.FilterMessage("Config changed").All()
+	requireT.Len(changed, 2)
+	requireT.Equal(map[string]any{
+		"key": flagAddressRateLimit,
+		"old": "1/24h",
+		"new": "2/24h",
+	}, changed[0].ContextMap())
+	requireT.Equal(map[string]any{"key": flagAPIKeys}, changed[1].ContextMap())
+	for _, entry := range logs.All() {
+		for _, value := range entry.ContextMap() {
+			requireT.NotContains(value, "secret")
+		}
+	}
+
+	// change requiring restart is refused and the running value is kept
+	refused := logs.FilterMessage("Config changes requiring restart are ignored").All()
+	requireT.Len(refused, 1)
+	requireT.Equal([]any{flagBatchSize}, refused[0].ContextMap()["keys"])
+	requireT.Equal("10", r.applied[flagBatchSize])
+	requireT.Equal("2/24h", r.applied[flagAddressRateLimit])
+
+	// reloadable changes are applied
+	requireT.Len(logs.FilterMessage("Config reloaded").All(), 1)
+	requireT.True(addressLimiter.Reserve("address"))
+	requireT.False(addressLimiter.Reserve("address"))
+
+	// refused change is reported again, as the faucet still runs with the old value
+	logs = reload()
+	requireT.Len(logs.FilterMessage("Config changes requiring restart are ignored").All(), 1)
+	requireT.Len(logs.FilterMessage("No config changes to apply").All(), 1)
+	requireT.Empty(logs.FilterMessage("Config changed").All())
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pkg/errors"
+
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+)
+
+// keyringBackends are the supported backends of the keyring directory. Empty backend means the keys are read from
+// the mnemonic file.
+var keyringBackends = map[string]bool{
+	"":                  true,
+	keyring.BackendFile: true,
+	keyring.BackendTest: true,
+}
+
+// newKeyringFromDir opens the existing keyring directory, e.g. created by `cored keys add`, and returns the addresses
+// of the keys used as funding accounts.
+func newKeyringFromDir(
+	backend, dir string,
+	keyNames []string,
+	passphraseFilePath string,
+	ctx client.Context,
+) (keyring.Keyring, []sdk.AccAddress, error) {
+	if len(keyNames) == 0 {
+		return nil, nil, errors.Errorf("--%s must be set to use the keyring", flagKeyNames)
+	}
+
+	var input io.Reader = os.Stdin
+	if backend == keyring.BackendFile {
+		passphrase, err := readPassphrase(passphraseFilePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		input = strings.NewReader(string(passphrase) + "\n")
+	}
+
+	kr, err := keyring.New(sdk.KeyringServiceName(), backend, dir, input, ctx.Codec())
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to open %s keyring at %s", backend, dir)
+	}
+
+	addresses := make([]sdk.AccAddress, 0, len(keyNames))
+	for _, name := range keyNames {
+		record, err := kr.Key(name)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to get key %q", name)
+		}
+		address, err := record.GetAddress()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to get address of key %q", name)
+		}
+		addresses = append(addresses, address)
+	}
+
+	return kr, addresses, nil
+}
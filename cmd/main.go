@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
 	"net/url"
@@ -34,23 +35,87 @@ import (
 	"github.com/CoreumFoundation/faucet/client/coreum"
 	"github.com/CoreumFoundation/faucet/http"
 	"github.com/CoreumFoundation/faucet/pkg/config"
+	faucethttp "github.com/CoreumFoundation/faucet/pkg/http"
+	"github.com/CoreumFoundation/faucet/pkg/idempotency"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
 	"github.com/CoreumFoundation/faucet/pkg/limiter"
 	"github.com/CoreumFoundation/faucet/pkg/logger"
 	"github.com/CoreumFoundation/faucet/pkg/signal"
 )
 
 const (
-	flagChainID           = "chain-id"
-	flagNode              = "node"
-	flagAddress           = "address"
-	flagMonitoringAddress = "monitoring-address"
-	flagTransferAmount    = "transfer-amount"
-	flagMnemonicFilePath  = "key-path-mnemonic"
-	flagIPRateLimit       = "ip-rate-limit"
+	flagConfig                  = "config"
+	flagChainID                 = "chain-id"
+	flagNode                    = "node"
+	flagNodeHealthCheckInterval = "node-health-check-interval"
+	flagNodeMaxBlockLag         = "node-max-block-lag"
+	flagAddress                 = "address"
+	flagMonitoringAddress       = "monitoring-address"
+	flagTransferAmount          = "transfer-amount"
+	flagTransferCoins           = "transfer-coins"
+	flagMaxTransferCoins        = "max-transfer-coins"
+	flagMnemonicFilePath        = "key-path-mnemonic"
+	flagPassphraseFilePath      = "key-passphrase-file"
+	flagKeyringBackend          = "keyring-backend"
+	flagKeyringDir              = "keyring-dir"
+	flagKeyNames                = "key-names"
+	flagFundingAccounts         = "funding-accounts"
+	flagKeyReloadInterval       = "key-reload-interval"
+	flagRebalanceLowWaterMark   = "rebalance-low-water-mark"
+	flagRebalanceTarget         = "rebalance-target"
+	flagRebalanceDonor          = "rebalance-donor"
+	flagRebalanceInterval       = "rebalance-interval"
+	flagRebalanceDryRun         = "rebalance-dry-run"
+	flagTreasuryMnemonicPath    = "treasury-key-path-mnemonic"
+	flagTreasuryKeyName         = "treasury-key-name"
+	flagTreasuryThreshold       = "treasury-threshold"
+	flagTreasuryTarget          = "treasury-target"
+	flagTreasuryDailyBudget     = "treasury-daily-budget"
+	flagTreasuryInterval        = "treasury-interval"
+	flagIPRateLimit             = "ip-rate-limit"
+	flagIPv4Prefix              = "ip-rate-limit-ipv4-prefix"
+	flagIPv6Prefix              = "ip-rate-limit-ipv6-prefix"
+	flagSubnetRateLimit         = "subnet-rate-limit"
+	flagSubnetIPv4Prefix        = "subnet-rate-limit-ipv4-prefix"
+	flagSubnetIPv6Prefix        = "subnet-rate-limit-ipv6-prefix"
+	flagAddressRateLimit        = "address-rate-limit"
+	flagLedgerPath              = "ledger-path"
+	flagIdempotencyWindow       = "idempotency-window"
+	flagAccountMaxFailures      = "funding-account-max-failures"
+	flagAccountReadmitAfter     = "funding-account-readmit-after"
+	flagAccountMinBalance       = "funding-account-min-balance"
+	flagAccountMaxPendingTxs    = "funding-account-max-pending-txs"
+	flagBatchSize               = "batch-size"
+	flagBatchLinger             = "batch-linger"
+	flagBatchMaxGas             = "batch-max-gas"
+	flagDuplicateRequests       = "duplicate-requests"
+	flagQueueMaxDepth           = "queue-max-depth"
+	flagQueueEnqueueTimeout     = "queue-enqueue-timeout"
+	flagLaneWeights             = "lane-weights"
+	flagInternalAPIKeys         = "internal-api-keys"
+	flagAPIKeys                 = "api-keys"
+	flagShutdownDrainTimeout    = "shutdown-drain-timeout"
+)
+
+const (
+	// gasParamsQueryTimeout is how long the gas params of the chain are queried on startup.
+	gasParamsQueryTimeout = 30 * time.Second
+	// maxFundingAccounts is the maximum number of funding accounts derived from each mnemonic, as each of them
+	// is derived separately on startup and on each reload.
+	maxFundingAccounts = 100
 )
 
 func main() {
-	ctx, log, cfg := setup()
+	if len(os.Args) > 1 && os.Args[1] == cmdEncryptMnemonic {
+		encryptMnemonicCmd(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 2 && os.Args[1] == cmdConfig && os.Args[2] == cmdConfigPrint {
+		printConfigCmd(os.Args[3:])
+		return
+	}
+
+	ctx, log, opts, cfg := setup(os.Args[1:])
 	if cfg.help {
 		return
 	}
@@ -59,7 +124,8 @@ func main() {
 		zap.String("address", cfg.address),
 		zap.String("chainID", cfg.chainID),
 		zap.String("mnemonicFilePath", cfg.mnemonicFilePath),
-		zap.String("node", cfg.node))
+		zap.String("keyringBackend", cfg.keyringBackend),
+		zap.Strings("nodes", cfg.nodes))
 
 	network, err := coreumconfig.NetworkConfigByChainID(constant.ChainID(cfg.chainID))
 	if err != nil {
@@ -81,14 +147,12 @@ func main() {
 		WithBroadcastMode(flags.BroadcastSync).
 		WithAwaitTx(true)
 
-	clientCtx = addClient(cfg, log, clientCtx)
-
-	transferAmount := sdk.Coin{
-		Amount: sdkmath.NewInt(cfg.transferAmount),
-		Denom:  network.Denom(),
-	}
+	transferAmount, maxTransferAmount := transferAmounts(cfg, network.Denom())
+	log.Info("transfer amount",
+		zap.Stringer("coins", transferAmount),
+		zap.Stringer("maxCoins", maxTransferAmount))
 
-	kr, addresses, err := newKeyringFromFile(cfg.mnemonicFilePath, clientCtx)
+	kr, addresses, err := newKeyring(cfg, clientCtx)
 	if err != nil {
 		log.Fatal(
 			"Unable to create keyring",
@@ -97,7 +161,23 @@ func main() {
 		)
 	}
 
-	clientCtx = clientCtx.WithKeyring(coreumkeyring.NewConcurrentSafeKeyring(kr))
+	var treasuryAddress sdk.AccAddress
+	if cfg.treasuryThreshold > 0 {
+		treasuryAddress, err = addTreasuryKey(cfg, kr, clientCtx)
+		if err != nil {
+			log.Fatal("Unable to add treasury key", zap.Error(err))
+		}
+		for _, address := range addresses {
+			if address.Equals(treasuryAddress) {
+				log.Fatal("Treasury must not be a funding account", zap.Stringer("treasury", treasuryAddress))
+			}
+		}
+		log.Info("treasury address", zap.Stringer("address", treasuryAddress))
+	}
+
+	// keyring is shared with the key reloader which modifies it while the txs are signed
+	kr = coreumkeyring.NewConcurrentSafeKeyring(kr)
+	clientCtx = clientCtx.WithKeyring(kr)
 
 	var addrList []string
 	for _, addr := range addresses {
@@ -110,46 +190,138 @@ func main() {
 		WithKeybase(clientCtx.Keyring()).
 		WithChainID(string(network.ChainID())).
 		WithSignMode(signing.SignMode_SIGN_MODE_DIRECT)
+	nodePool := coreum.NewNodePool(clientCtx, dialNodes(cfg, log), cfg.nodeHealthCheckInterval, cfg.nodeMaxBlockLag)
+	gasEstimator, err := queryGasEstimator(ctx, nodePool)
+	if err != nil {
+		log.Fatal("Unable to query gas params", zap.Error(err))
+	}
 	cl := coreum.New(
 		network,
-		clientCtx,
+		nodePool,
 		txf,
+		gasEstimator,
 	)
 
+	ledgerStore, err := ledger.Open(cfg.ledgerPath)
+	if err != nil {
+		log.Fatal("Unable to open ledger", zap.Error(err), zap.String("path", cfg.ledgerPath))
+	}
+
+	startedAt := time.Now().UTC()
 	err = parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
-		batcher := coreum.NewBatcher(cl, addresses, 10)
-		application := app.New(clientCtx, batcher, network, transferAmount)
+		batcherConfig := coreum.DefaultBatcherConfig()
+		batcherConfig.BatchSize = cfg.batchSize
+		batcherConfig.Linger = cfg.batchLinger
+		batcherConfig.MaxGas = cfg.batchMaxGas
+		batcherConfig.GasEstimator = gasEstimator
+		batcherConfig.Duplicates = cfg.duplicateRequests
+		batcherConfig.MaxQueueDepth = cfg.queueMaxDepth
+		batcherConfig.EnqueueTimeout = cfg.queueEnqueueTimeout
+		batcherConfig.LaneWeights = cfg.laneWeights
+		batcherConfig.MaxConsecutiveFailures = cfg.accountMaxFailures
+		batcherConfig.ReadmitAfter = cfg.accountReadmitAfter
+		batcherConfig.MinBalance = sdkmath.NewInt(cfg.accountMinBalance)
+		batcherConfig.MaxPendingTxs = cfg.accountMaxPendingTxs
+		batcherConfig.DrainTimeout = cfg.shutdownDrainTimeout
+		batcherConfig.Progress = app.RecordProgress(ledgerStore)
+		batcher := coreum.NewBatcher(cl, addresses, batcherConfig)
+		rebalancer := coreum.NewRebalancer(cl, batcher, coreum.RebalancerConfig{
+			Denom:        network.Denom(),
+			LowWaterMark: sdkmath.NewInt(cfg.rebalanceLowWaterMark),
+			Target:       sdkmath.NewInt(cfg.rebalanceTarget),
+			Donor:        rebalanceDonor(log, cfg, addresses),
+			Interval:     cfg.rebalanceInterval,
+			DryRun:       cfg.rebalanceDryRun,
+		})
+		addressLimiter := limiter.NewWeightedWindowLimiter(cfg.addressRateLimit.howMany, cfg.addressRateLimit.period)
+		application := app.New(
+			clientCtx,
+			batcher,
+			ledgerStore,
+			nodePool,
+			network,
+			transferAmount,
+			maxTransferAmount,
+			addressLimiter,
+		)
 		ipLimiter := limiter.NewWeightedWindowLimiter(cfg.ipRateLimit.howMany, cfg.ipRateLimit.period)
+		subnetLimiter := limiter.NewWeightedWindowLimiter(cfg.subnetRateLimit.howMany, cfg.subnetRateLimit.period)
+		networkLimiter := limiter.NewSubnetLimiter(ipLimiter, cfg.ipPrefixes, subnetLimiter, cfg.subnetPrefixes)
+		idempotencyCache := idempotency.NewCache(cfg.idempotencyWindow)
+		serverAPIKeys := http.NewAPIKeys(apiKeys(cfg))
 		//nolint:contextcheck
-		server := http.New(application, ipLimiter, log)
-
-		spawn("batcher", parallel.Fail, batcher.Run)
+		server := http.New(application, networkLimiter, idempotencyCache, serverAPIKeys, log)
+		configReloader := newConfigReloader(
+			os.Args[1:], opts, network.Denom(), application, ipLimiter, subnetLimiter, addressLimiter, serverAPIKeys)
+
+		spawn("nodePool", parallel.Fail, nodePool.Run)
+		// server keeps running until the batcher is drained, so the new requests are rejected instead of refused
+		serverCtx, stopServer := context.WithCancel(faucethttp.NewReopenedCtx(ctx))
+		spawn("batcher", parallel.Fail, func(ctx context.Context) error {
+			defer stopServer()
+			return batcher.Run(ctx)
+		})
+		spawn("reconcile", parallel.Continue, func(ctx context.Context) error {
+			return app.Reconcile(ctx, ledgerStore, cl, startedAt)
+		})
+		spawn("configReloader", parallel.Fail, configReloader.Run)
 		spawn("limiterCleanup", parallel.Fail, ipLimiter.Run)
+		spawn("subnetLimiterCleanup", parallel.Fail, subnetLimiter.Run)
+		spawn("addressLimiterCleanup", parallel.Fail, addressLimiter.Run)
+		spawn("idempotencyCleanup", parallel.Fail, idempotencyCache.Run)
 		spawn("server", parallel.Fail, func(ctx context.Context) error {
-			return server.ListenAndServe(ctx, cfg.address)
+			//nolint:contextcheck // server is stopped once the batcher is drained
+			return server.ListenAndServe(serverCtx, cfg.address)
 		})
+		treasury := coreum.NewTreasury(cl, ledgerStore, batcher, coreum.TreasuryConfig{
+			Denom:       network.Denom(),
+			Address:     treasuryAddress,
+			Threshold:   sdkmath.NewInt(cfg.treasuryThreshold),
+			Target:      sdkmath.NewInt(cfg.treasuryTarget),
+			DailyBudget: sdkmath.NewInt(cfg.treasuryDailyBudget),
+			Interval:    cfg.treasuryInterval,
+		})
+
+		// keys in the keyring directory are fixed, so only the mnemonic file is reloaded
+		if cfg.keyringBackend == "" {
+			keyReloader, err := newKeyReloader(cfg, kr, batcher, cl, treasuryAddress, clientCtx)
+			if err != nil {
+				return err
+			}
+			spawn("keyReloader", parallel.Fail, keyReloader.Run)
+		}
+		if cfg.rebalanceLowWaterMark > 0 {
+			spawn("rebalancer", parallel.Fail, rebalancer.Run)
+		}
+		if cfg.treasuryThreshold > 0 {
+			spawn("treasury", parallel.Fail, treasury.Run)
+		}
 		spawn("monitoring", parallel.Fail, func(ctx context.Context) error {
-			return app.RunMonitoring(ctx, cfg.monitoringAddress, clientCtx, addresses, network.Denom())
+			return app.RunMonitoring(
+				ctx, cfg.monitoringAddress, nodePool, batcher, network.Denom(), batcher,
+				nodePool, batcher, rebalancer, treasury)
 		})
 
 		return nil
 	})
 
-	if err != nil {
+	if closeErr := ledgerStore.Close(); closeErr != nil {
+		log.Error("Unable to close ledger", zap.Error(closeErr))
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
 		log.Fatal("Error on ListenAndServe", zap.Error(err))
 	}
 }
 
-func addClient(cfg cfg, log *zap.Logger, clientCtx client.Context) client.Context {
-	nodeURL, err := url.Parse(cfg.node)
-	if err != nil {
-		log.Fatal(
-			"Unable to decode node url",
-			zap.Error(err),
-			zap.String("url", cfg.node),
-		)
-	}
+// queryGasEstimator reads the gas params of the chain once, they are not expected to change while the faucet runs.
+func queryGasEstimator(ctx context.Context, nodePool *coreum.NodePool) (coreum.GasEstimator, error) {
+	ctx, cancel := context.WithTimeout(ctx, gasParamsQueryTimeout)
+	defer cancel()
 
+	return coreum.QueryGasEstimator(ctx, nodePool.ClientContext())
+}
+
+func dialNodes(cfg cfg, log *zap.Logger) []coreum.Node {
 	encodingConfig := coreumconfig.NewEncodingConfig(auth.AppModuleBasic{})
 
 	pc, ok := encodingConfig.Codec.(codec.GRPCCodecProvider)
@@ -157,6 +329,26 @@ func addClient(cfg cfg, log *zap.Logger, clientCtx client.Context) client.Contex
 		panic("failed to cast codec to codec.GRPCCodecProvider")
 	}
 
+	nodes := make([]coreum.Node, 0, len(cfg.nodes))
+	for _, node := range cfg.nodes {
+		nodes = append(nodes, coreum.Node{
+			URL:  node,
+			Conn: dialNode(node, pc, log),
+		})
+	}
+	return nodes
+}
+
+func dialNode(node string, pc codec.GRPCCodecProvider, log *zap.Logger) *grpc.ClientConn {
+	nodeURL, err := url.Parse(node)
+	if err != nil {
+		log.Fatal(
+			"Unable to decode node url",
+			zap.Error(err),
+			zap.String("url", node),
+		)
+	}
+
 	// tls grpc
 	if nodeURL.Scheme == "https" {
 		grpcClient, err := grpc.NewClient(
@@ -168,14 +360,14 @@ func addClient(cfg cfg, log *zap.Logger, clientCtx client.Context) client.Contex
 			panic(err)
 		}
 
-		return clientCtx.WithGRPCClient(grpcClient)
+		return grpcClient
 	}
 
 	// no-tls grpc
 	host := nodeURL.Host
 	// it is possible that protocol wasn't provided, in such scenario we use the node as a host to dial
 	if host == "" {
-		host = cfg.node
+		host = node
 	}
 	grpcClient, err := grpc.NewClient(
 		host,
@@ -189,33 +381,88 @@ func addClient(cfg cfg, log *zap.Logger, clientCtx client.Context) client.Contex
 		)
 	}
 
-	return clientCtx.WithGRPCClient(grpcClient)
+	return grpcClient
+}
+
+// options are the flags and the logger level, kept to reload the config while running.
+type options struct {
+	flagSet *pflag.FlagSet
+	level   zap.AtomicLevel
 }
 
-func setup() (context.Context, *zap.Logger, cfg) {
+func setup(args []string) (context.Context, *zap.Logger, options, cfg) {
 	loggerConfig, loggerFlagRegistry := logger.ConfigureWithCLI(logger.ServiceDefaultConfig)
 	log := logger.New(loggerConfig)
-	ctx := logger.WithLogger(context.Background(), log)
-	ctx = signal.TerminateSignal(ctx)
 
 	flagSet := pflag.NewFlagSet("faucet", pflag.ExitOnError)
 	loggerFlagRegistry(flagSet)
-	cfg := getConfig(log, flagSet)
+	cfg := getConfig(log, flagSet, args)
 	if cfg.help {
 		flagSet.PrintDefaults()
 	}
-	return ctx, log, cfg
+
+	// logger flags might be set by the config file or env vars too
+	loggerConfig, err := logger.ApplyFlags(loggerConfig, flagSet)
+	if err != nil {
+		log.Fatal("Error getting logger config", zap.Error(err))
+	}
+	log, level := logger.NewWithLevel(loggerConfig)
+	ctx := logger.WithLogger(context.Background(), log)
+	ctx = signal.TerminateSignal(ctx)
+	return ctx, log, options{flagSet: flagSet, level: level}, cfg
 }
 
 type cfg struct {
-	chainID           string
-	node              string
-	mnemonicFilePath  string
-	address           string
-	monitoringAddress string
-	transferAmount    int64
-	ipRateLimit       rateLimit
-	help              bool
+	configPath              string
+	chainID                 string
+	nodes                   []string
+	mnemonicFilePath        string
+	passphraseFilePath      string
+	keyringBackend          string
+	keyringDir              string
+	keyNames                []string
+	fundingAccounts         uint32
+	keyReloadInterval       time.Duration
+	rebalanceLowWaterMark   int64
+	rebalanceTarget         int64
+	rebalanceDonor          string
+	rebalanceInterval       time.Duration
+	rebalanceDryRun         bool
+	treasuryMnemonicPath    string
+	treasuryKeyName         string
+	treasuryThreshold       int64
+	treasuryTarget          int64
+	treasuryDailyBudget     int64
+	treasuryInterval        time.Duration
+	address                 string
+	monitoringAddress       string
+	ledgerPath              string
+	transferAmount          int64
+	transferCoins           sdk.Coins
+	maxTransferCoins        sdk.Coins
+	ipRateLimit             rateLimit
+	ipPrefixes              limiter.Prefixes
+	subnetRateLimit         rateLimit
+	subnetPrefixes          limiter.Prefixes
+	addressRateLimit        rateLimit
+	idempotencyWindow       time.Duration
+	nodeHealthCheckInterval time.Duration
+	nodeMaxBlockLag         int64
+	accountMaxFailures      int
+	accountReadmitAfter     time.Duration
+	accountMinBalance       int64
+	accountMaxPendingTxs    int
+	batchSize               int
+	batchLinger             time.Duration
+	batchMaxGas             uint64
+	duplicateRequests       coreum.DuplicatePolicy
+	queueMaxDepth           int
+	queueEnqueueTimeout     time.Duration
+	laneWeights             coreum.LaneWeights
+	internalAPIKeys         []string
+	apiKeys                 []string
+	shutdownDrainTimeout    time.Duration
+	help                    bool
 }
 
 func parseRateLimit(limit string) (rateLimit, error) {
@@ -243,67 +490,363 @@ type rateLimit struct {
 	period  time.Duration
 }
 
-func getConfig(log *zap.Logger, flagSet *pflag.FlagSet) cfg {
+func getConfig(log *zap.Logger, flagSet *pflag.FlagSet, args []string) cfg {
+	conf, err := parseConfig(flagSet, args)
+	if err != nil {
+		log.Fatal("Error getting config", zap.Error(err))
+	}
+	return conf
+}
+
+// parseConfig defines the flags, reads their values from the args, config file and env vars and validates them.
+func parseConfig(flagSet *pflag.FlagSet, args []string) (cfg, error) {
 	var conf cfg
-	var ipRateLimit string
+	var ipRateLimit, subnetRateLimit, addressRateLimit, transferCoins, maxTransferCoins, duplicateRequests string
+	var laneWeights []int
+	defaultLaneWeights := coreum.DefaultLaneWeights()
 
+	flagSet.StringVar(&conf.configPath, flagConfig, "",
+		"path to the YAML (.yaml, .yml) or TOML (.toml) config file, its keys are the flag names, "+
+			"env vars and flags take precedence over it")
 	flagSet.StringVar(&conf.chainID, flagChainID, string(constant.ChainIDDev), "The network chain ID")
-	flagSet.StringVar(&conf.node, flagNode, "localhost:9090", "<host>:<port> to Tendermint GRPC endpoint for this chain")
+	flagSet.StringSliceVar(&conf.nodes, flagNode, []string{"localhost:9090"},
+		"comma separated list of <host>:<port> of Tendermint GRPC endpoints for this chain, "+
+			"the first healthy one is used")
+	flagSet.DurationVar(&conf.nodeHealthCheckInterval, flagNodeHealthCheckInterval, 10*time.Second,
+		"how often the health of the nodes is checked")
+	flagSet.Int64Var(&conf.nodeMaxBlockLag, flagNodeMaxBlockLag, 5,
+		"node lagging behind the highest one by more blocks is considered unhealthy")
 	flagSet.StringVar(&conf.address, flagAddress, ":8090", "<host>:<port> address to start listening for http requests")
 	flagSet.StringVar(&conf.monitoringAddress, flagMonitoringAddress, ":8091",
 		"<host>:<port> address to expose metrics to")
 	flagSet.Int64Var(&conf.transferAmount, flagTransferAmount, 100000000,
-		"how much to transfer in each request")
+		"how much of the network denom to transfer in each request, ignored if --transfer-coins is set")
+	flagSet.StringVar(&transferCoins, flagTransferCoins, "",
+		"comma separated list of coins to transfer in each request, e.g. 100000000ucore,5000utest-devcore1...")
+	flagSet.StringVar(&maxTransferCoins, flagMaxTransferCoins, "",
+		"comma separated list of the denoms a caller may request together with the maximum amount of each, "+
+			"defaults to the transfer coins")
 	flagSet.StringVar(&conf.mnemonicFilePath, flagMnemonicFilePath, "mnemonic.txt",
 		"path to file containing mnemonic for private keys, each line containing one mnemonic")
+	flagSet.StringVar(&conf.passphraseFilePath, flagPassphraseFilePath, "",
+		"path to file containing the passphrase of the encrypted mnemonic file or the file keyring, "+
+			"if not set the passphrase is read from "+envPassphrase+" env var")
+	flagSet.StringVar(&conf.keyringBackend, flagKeyringBackend, "",
+		"backend of the keyring directory holding the funding keys: file | test, "+
+			"if not set the keys are read from the mnemonic file")
+	flagSet.StringVar(&conf.keyringDir, flagKeyringDir, ".",
+		"directory containing the keyring, e.g. the home directory of cored")
+	flagSet.StringSliceVar(&conf.keyNames, flagKeyNames, nil,
+		"comma separated list of names of the keys in the keyring used as funding accounts")
+	flagSet.Uint32Var(&conf.fundingAccounts, flagFundingAccounts, 1,
+		"number of funding accounts derived from each mnemonic, using incrementing address indexes, "+
+			"at most "+strconv.Itoa(maxFundingAccounts))
+	flagSet.DurationVar(&conf.keyReloadInterval, flagKeyReloadInterval, 10*time.Second,
+		"how often the mnemonic file is checked for modifications to reload the funding keys, 0 disables it, "+
+			"keys are also reloaded on SIGHUP")
+	flagSet.Int64Var(&conf.rebalanceLowWaterMark, flagRebalanceLowWaterMark, 0,
+		"balance of the network denom below which the funding account is topped up from another one, 0 disables it")
+	flagSet.Int64Var(&conf.rebalanceTarget, flagRebalanceTarget, 0,
+		"balance the funding account is topped up to, the donor is never drained below it, "+
+			"defaults to twice the low-water mark")
+	flagSet.StringVar(&conf.rebalanceDonor, flagRebalanceDonor, "",
+		"address of the funding account the tokens are taken from, defaults to the richest one")
+	flagSet.DurationVar(&conf.rebalanceInterval, flagRebalanceInterval, time.Minute,
+		"how often the balances of the funding accounts are checked for rebalancing")
+	flagSet.BoolVar(&conf.rebalanceDryRun, flagRebalanceDryRun, false,
+		"only log the transfers which would rebalance the funding accounts")
+	flagSet.StringVar(&conf.treasuryMnemonicPath, flagTreasuryMnemonicPath, "",
+		"path to file containing mnemonic of the treasury refilling the funding accounts, "+
+			"encrypted the same way as the funding mnemonics")
+	flagSet.StringVar(&conf.treasuryKeyName, flagTreasuryKeyName, "",
+		"name of the treasury key in the keyring, used instead of the treasury mnemonic file if the keyring is set")
+	flagSet.Int64Var(&conf.treasuryThreshold, flagTreasuryThreshold, 0,
+		"total balance of the network denom held by the funding accounts below which they are refilled "+
+			"from the treasury, 0 disables the treasury")
+	flagSet.Int64Var(&conf.treasuryTarget, flagTreasuryTarget, 0,
+		"total balance the funding accounts are refilled to, defaults to twice the threshold")
+	flagSet.Int64Var(&conf.treasuryDailyBudget, flagTreasuryDailyBudget, 0,
+		"maximum amount of the network denom sent from the treasury during the UTC day")
+	flagSet.DurationVar(&conf.treasuryInterval, flagTreasuryInterval, time.Minute,
+		"how often the total balance of the funding accounts is checked for refill")
+	flagSet.StringVar(&conf.ledgerPath, flagLedgerPath, "faucet.db",
+		"path to the database file recording all the fund requests")
 	flagSet.StringVar(&ipRateLimit, flagIPRateLimit, "2/1h",
-		"limit of requests per IP in the format <num-of-req>/<period>")
+		"limit of requests per IP network in the format <num-of-req>/<period>, "+
+			"request is rejected once more than <num-of-req> requests have been counted")
+	flagSet.IntVar(&conf.ipPrefixes.IPv4, flagIPv4Prefix, 32,
+		"length of the prefix the IPv4 addresses are aggregated by when counted by the IP rate limit")
+	flagSet.IntVar(&conf.ipPrefixes.IPv6, flagIPv6Prefix, 64,
+		"length of the prefix the IPv6 addresses are aggregated by when counted by the IP rate limit")
+	flagSet.StringVar(&subnetRateLimit, flagSubnetRateLimit, "0/1h",
+		"limit of requests per larger IP network in the format <num-of-req>/<period>, "+
+			"exactly <num-of-req> requests are allowed, 0 requests disables it")
+	flagSet.IntVar(&conf.subnetPrefixes.IPv4, flagSubnetIPv4Prefix, 16,
+		"length of the prefix the IPv4 addresses are aggregated by when counted by the subnet rate limit")
+	flagSet.IntVar(&conf.subnetPrefixes.IPv6, flagSubnetIPv6Prefix, 48,
+		"length of the prefix the IPv6 addresses are aggregated by when counted by the subnet rate limit")
+	flagSet.StringVar(&addressRateLimit, flagAddressRateLimit, "1/24h",
+		"limit of requests per destination address in the format <num-of-req>/<period>, 0 requests disables it")
+	flagSet.DurationVar(&conf.idempotencyWindow, flagIdempotencyWindow, 24*time.Hour,
+		"how long the responses are stored to be replayed for the requests with the same Idempotency-Key header")
+	flagSet.IntVar(&conf.accountMaxFailures, flagAccountMaxFailures, 3,
+		"number of failed transactions in a row after which the funding account is excluded")
+	flagSet.DurationVar(&conf.accountReadmitAfter, flagAccountReadmitAfter, time.Minute,
+		"how long the funding account excluded due to failures is not used")
+	flagSet.Int64Var(&conf.accountMinBalance, flagAccountMinBalance, 0,
+		"balance of the network denom below which the funding account is excluded, 0 disables the check")
+	flagSet.IntVar(&conf.batchSize, flagBatchSize, 10, "maximum number of requests sent in a single transaction")
+	flagSet.DurationVar(&conf.batchLinger, flagBatchLinger, 100*time.Millisecond,
+		"how long the batch waits for more requests after the first one is received")
+	flagSet.Uint64Var(&conf.batchMaxGas, flagBatchMaxGas, 0,
+		"maximum gas of the transaction sending the batch, 0 disables the limit")
+	flagSet.StringVar(&duplicateRequests, flagDuplicateRequests, string(coreum.DuplicatesCoalesce),
+		"how the requests for the same address in the same batch are handled: allow | coalesce | reject")
+	flagSet.IntVar(&conf.queueMaxDepth, flagQueueMaxDepth, 1000,
+		"maximum number of requests waiting to be batched, requests above the limit are rejected")
+	flagSet.DurationVar(&conf.queueEnqueueTimeout, flagQueueEnqueueTimeout, 0,
+		"how long the request waits for the space in the full queue before it is rejected")
+	flagSet.IntSliceVar(&laneWeights, flagLaneWeights, defaultLaneWeights[:],
+		"comma separated weights of internal, authenticated and public requests, defining how many requests "+
+			"of each priority are batched in a row before the lower priority is served")
+	flagSet.StringSliceVar(&conf.internalAPIKeys, flagInternalAPIKeys, nil,
+		"comma separated list of API keys of the internal clients, their requests are served with the highest priority")
+	flagSet.StringSliceVar(&conf.apiKeys, flagAPIKeys, nil,
+		"comma separated list of API keys of the authenticated clients, their requests are served before public ones")
+	flagSet.IntVar(&conf.accountMaxPendingTxs, flagAccountMaxPendingTxs, 3,
+		"number of txs broadcast from the funding account which might wait to be committed at the same time")
+	flagSet.DurationVar(&conf.shutdownDrainTimeout, flagShutdownDrainTimeout, 30*time.Second,
+		"how long the accepted requests are processed after shutdown is started, new requests are rejected meanwhile")
 	flagSet.BoolVarP(&conf.help, "help", "h", false, "prints help")
-	_ = flagSet.Parse(os.Args[1:])
+	if err := flagSet.Parse(args); err != nil {
+		return cfg{}, errors.WithStack(err)
+	}
+
+	// config file path is needed before the env vars are applied, as they take precedence over the file
+	configPath := conf.configPath
+	if !flagSet.Changed(flagConfig) {
+		configPath = os.Getenv(config.EnvName("", flagConfig))
+	}
+	if configPath != "" {
+		if err := config.WithFile(flagSet, configPath); err != nil {
+			return cfg{}, err
+		}
+	}
+
+	err := config.WithEnv(flagSet, "")
+	if err != nil {
+		return cfg{}, errors.Wrap(err, "unable to read env vars")
+	}
 
-	var err error
 	conf.ipRateLimit, err = parseRateLimit(ipRateLimit)
 	if err != nil {
-		log.Fatal("Error parsing IP rate limit", zap.Error(err))
+		return cfg{}, errors.Wrap(err, "unable to parse IP rate limit")
+	}
+	if err := conf.ipPrefixes.Validate(); err != nil {
+		return cfg{}, errors.Wrap(err, "invalid IP rate limit prefix")
+	}
+	conf.subnetRateLimit, err = parseRateLimit(subnetRateLimit)
+	if err != nil {
+		return cfg{}, errors.Wrap(err, "unable to parse subnet rate limit")
+	}
+	if err := conf.subnetPrefixes.Validate(); err != nil {
+		return cfg{}, errors.Wrap(err, "invalid subnet rate limit prefix")
+	}
+	if conf.subnetPrefixes.IPv4 > conf.ipPrefixes.IPv4 || conf.subnetPrefixes.IPv6 > conf.ipPrefixes.IPv6 {
+		return cfg{}, errors.New("subnet rate limit prefixes must not be longer than the IP rate limit ones")
+	}
+	conf.addressRateLimit, err = parseRateLimit(addressRateLimit)
+	if err != nil {
+		return cfg{}, errors.Wrap(err, "unable to parse address rate limit")
 	}
 
-	err = config.WithEnv(flagSet, "")
+	conf.transferCoins, err = sdk.ParseCoinsNormalized(transferCoins)
 	if err != nil {
-		log.Fatal("Error getting config", zap.Error(err))
+		return cfg{}, errors.Wrap(err, "unable to parse transfer coins")
 	}
-	return conf
+	conf.maxTransferCoins, err = sdk.ParseCoinsNormalized(maxTransferCoins)
+	if err != nil {
+		return cfg{}, errors.Wrap(err, "unable to parse max transfer coins")
+	}
+	conf.duplicateRequests = coreum.DuplicatePolicy(duplicateRequests)
+	err = conf.duplicateRequests.Validate()
+	if err != nil {
+		return cfg{}, errors.Wrap(err, "unable to parse duplicate requests policy")
+	}
+	if !keyringBackends[conf.keyringBackend] {
+		return cfg{}, errors.Errorf("unsupported keyring backend %q", conf.keyringBackend)
+	}
+	if conf.batchSize <= 0 {
+		return cfg{}, errors.New("batch size must be positive")
+	}
+	if conf.fundingAccounts == 0 || conf.fundingAccounts > maxFundingAccounts {
+		return cfg{}, errors.Errorf("number of funding accounts derived from each mnemonic must be between 1 and %d",
+			maxFundingAccounts)
+	}
+	// accounts are derived only from the mnemonic file
+	if conf.keyringBackend != "" && conf.fundingAccounts != 1 {
+		return cfg{}, errors.Errorf("--%s can't be used with --%s, keys are not derived from the keyring",
+			flagFundingAccounts, flagKeyringBackend)
+	}
+	if conf.rebalanceTarget == 0 {
+		conf.rebalanceTarget = 2 * conf.rebalanceLowWaterMark
+	}
+	if conf.rebalanceTarget < conf.rebalanceLowWaterMark {
+		return cfg{}, errors.New("rebalance target must not be lower than the low-water mark")
+	}
+	if conf.treasuryTarget == 0 {
+		conf.treasuryTarget = 2 * conf.treasuryThreshold
+	}
+	if conf.treasuryTarget < conf.treasuryThreshold {
+		return cfg{}, errors.New("treasury target must not be lower than the threshold")
+	}
+	if conf.treasuryThreshold > 0 && conf.treasuryDailyBudget <= 0 {
+		return cfg{}, errors.New("treasury daily budget must be set if the treasury is used")
+	}
+	if len(laneWeights) != len(conf.laneWeights) {
+		return cfg{}, errors.Errorf("expected %d lane weights, got %v", len(conf.laneWeights), laneWeights)
+	}
+	copy(conf.laneWeights[:], laneWeights)
+	return conf, nil
+}
+
+// transferAmounts returns the default and the maximum amounts sent by the faucet.
+func transferAmounts(cfg cfg, denom string) (sdk.Coins, sdk.Coins) {
+	transferAmount := cfg.transferCoins
+	if transferAmount.Empty() {
+		transferAmount = sdk.NewCoins(sdk.NewCoin(denom, sdkmath.NewInt(cfg.transferAmount)))
+	}
+	maxTransferAmount := cfg.maxTransferCoins
+	if maxTransferAmount.Empty() {
+		maxTransferAmount = transferAmount
+	}
+	return transferAmount, maxTransferAmount
 }
 
-func newKeyringFromFile(path string, ctx client.Context) (keyring.Keyring, []sdk.AccAddress, error) {
-	file, err := os.Open(path)
+// apiKeys returns the priorities of the requests sent with the configured API keys.
+func apiKeys(cfg cfg) map[string]coreum.Priority {
+	keys := map[string]coreum.Priority{}
+	for _, key := range cfg.apiKeys {
+		keys[key] = coreum.PriorityAuthenticated
+	}
+	for _, key := range cfg.internalAPIKeys {
+		keys[key] = coreum.PriorityInternal
+	}
+	return keys
+}
+
+// rebalanceDonor returns the designated donor of the rebalancer, it must be one of the funding accounts.
+func rebalanceDonor(log *zap.Logger, cfg cfg, addresses []sdk.AccAddress) sdk.AccAddress {
+	if cfg.rebalanceDonor == "" {
+		return nil
+	}
+	donor, err := sdk.AccAddressFromBech32(cfg.rebalanceDonor)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "unable to open file at %s", path)
+		log.Fatal("Invalid rebalance donor address", zap.Error(err))
+	}
+	for _, address := range addresses {
+		if address.Equals(donor) {
+			return donor
+		}
+	}
+	log.Fatal("Rebalance donor is not a funding account", zap.Stringer("donor", donor))
+	return nil
+}
+
+func newKeyring(cfg cfg, ctx client.Context) (keyring.Keyring, []sdk.AccAddress, error) {
+	if cfg.keyringBackend != "" {
+		return newKeyringFromDir(cfg.keyringBackend, cfg.keyringDir, cfg.keyNames, cfg.passphraseFilePath, ctx)
+	}
+	return newKeyringFromFile(cfg.mnemonicFilePath, cfg.passphraseFilePath, cfg.fundingAccounts, ctx)
+}
+
+// newKeyringFromFile derives accountsPerMnemonic funding accounts from each mnemonic in the file, using incrementing
+// address indexes of the BIP44 path.
+func newKeyringFromFile(
+	path, passphraseFilePath string,
+	accountsPerMnemonic uint32,
+	ctx client.Context,
+) (keyring.Keyring, []sdk.AccAddress, error) {
+	keys, err := readFundingKeys(path, passphraseFilePath, accountsPerMnemonic, ctx)
+	if err != nil {
+		return nil, nil, err
 	}
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
 	kr := keyring.NewInMemory(ctx.Codec())
-	var addresses []sdk.AccAddress
+	addresses := make([]sdk.AccAddress, 0, len(keys))
+	for _, key := range keys {
+		if err := addKey(kr, key); err != nil {
+			return nil, nil, err
+		}
+		addresses = append(addresses, key.address)
+	}
+	return kr, addresses, nil
+}
+
+// fundingKey is the key derived from the mnemonic at the address index.
+type fundingKey struct {
+	mnemonic string
+	hdPath   string
+	address  sdk.AccAddress
+}
+
+// readFundingKeys derives accountsPerMnemonic keys from each mnemonic in the file.
+func readFundingKeys(
+	path, passphraseFilePath string,
+	accountsPerMnemonic uint32,
+	ctx client.Context,
+) ([]fundingKey, error) {
+	content, err := readMnemonicFile(path, passphraseFilePath)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	var keys []fundingKey
 	for scanner.Scan() {
 		mnemonic := scanner.Text()
-		tempKr := keyring.NewInMemory(ctx.Codec())
-		info, err := tempKr.NewAccount("temp", mnemonic, "", sdk.GetConfig().GetFullBIP44Path(), hd.Secp256k1)
-		if err != nil {
-			return nil, nil, errors.Wrapf(err, "unable to parse mnemonic key")
-		}
-		address, err := info.GetAddress()
-		if err != nil {
-			return nil, nil, errors.Wrapf(err, "unable to get address")
-		}
-		addresses = append(addresses, address)
-		_, err = kr.NewAccount(address.String(), mnemonic, "", sdk.GetConfig().GetFullBIP44Path(), hd.Secp256k1)
-		if err != nil {
-			return nil, nil, errors.Wrapf(err, "unable to parse mnemonic key")
+		for index := range accountsPerMnemonic {
+			key, err := deriveKey(mnemonic, index, ctx)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
 		}
 	}
 
-	if len(addresses) == 0 {
-		return nil, nil, errors.New("could not parse any mnemonic")
+	if len(keys) == 0 {
+		return nil, errors.New("could not parse any mnemonic")
 	}
+	return keys, nil
+}
 
-	return kr, addresses, nil
+// deriveKey derives the key from the mnemonic at the address index.
+func deriveKey(mnemonic string, index uint32, ctx client.Context) (fundingKey, error) {
+	hdPath := hd.NewParams(sdk.GetConfig().GetPurpose(), sdk.GetConfig().GetCoinType(), 0, false, index).String()
+	tempKr := keyring.NewInMemory(ctx.Codec())
+	info, err := tempKr.NewAccount("temp", mnemonic, "", hdPath, hd.Secp256k1)
+	if err != nil {
+		return fundingKey{}, errors.Wrapf(err, "unable to parse mnemonic key")
+	}
+	address, err := info.GetAddress()
+	if err != nil {
+		return fundingKey{}, errors.Wrapf(err, "unable to get address")
+	}
+	return fundingKey{mnemonic: mnemonic, hdPath: hdPath, address: address}, nil
+}
+
+// addKey adds the key to the keyring. Key is named by its address.
+func addKey(kr keyring.Keyring, key fundingKey) error {
+	_, err := kr.NewAccount(key.address.String(), key.mnemonic, "", key.hdPath, hd.Secp256k1)
+	return errors.Wrapf(err, "unable to parse mnemonic key")
+}
+
+// addMnemonicKey adds the key derived from the mnemonic at the address index to the keyring.
+func addMnemonicKey(kr keyring.Keyring, mnemonic string, index uint32, ctx client.Context) (sdk.AccAddress, error) {
+	key, err := deriveKey(mnemonic, index, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := addKey(kr, key); err != nil {
+		return nil, err
+	}
+	return key.address, nil
 }
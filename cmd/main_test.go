@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+)
+
+func newTestClientContext() client.Context {
+	return client.NewContext(client.DefaultContextConfig(), auth.AppModuleBasic{})
+}
+
+// newTestMnemonic generates new mnemonic and returns it together with the address of the key derived from it
+// along the full BIP44 path, the way the single funding account used to be derived.
+func newTestMnemonic(t *testing.T, ctx client.Context) (string, sdk.AccAddress) {
+	requireT := require.New(t)
+
+	kr := keyring.NewInMemory(ctx.Codec())
+	info, mnemonic, err := kr.NewMnemonic("key", keyring.English, sdk.GetConfig().GetFullBIP44Path(), "",
+		hd.Secp256k1)
+	requireT.NoError(err)
+	address, err := info.GetAddress()
+	requireT.NoError(err)
+	return mnemonic, address
+}
+
+func TestReadFundingKeys(t *testing.T) {
+	requireT := require.New(t)
+
+	ctx := newTestClientContext()
+	mnemonic1, address1 := newTestMnemonic(t, ctx)
+	mnemonic2, address2 := newTestMnemonic(t, ctx)
+	path := filepath.Join(t.TempDir(), "mnemonics")
+	requireT.NoError(os.WriteFile(path, []byte(mnemonic1+"\n"+mnemonic2+"\n"), 0o600))
+
+	keys, err := readFundingKeys(path, "", 3, ctx)
+	requireT.NoError(err)
+	requireT.Len(keys, 6)
+
+	// account 0 is the one used when the single account was derived from each mnemonic
+	requireT.Equal(address1, keys[0].address)
+	requireT.Equal(address2, keys[3].address)
+
+	for i, key := range keys {
+		mnemonic := mnemonic1
+		if i >= 3 {
+			mnemonic = mnemonic2
+		}
+		index := uint32(i % 3)
+		hdPath := hd.NewParams(sdk.GetConfig().GetPurpose(), sdk.GetConfig().GetCoinType(), 0, false, index).String()
+		requireT.Equal(hdPath, key.hdPath)
+		requireT.Equal(mnemonic, key.mnemonic)
+
+		kr := keyring.NewInMemory(ctx.Codec())
+		info, err := kr.NewAccount("key", mnemonic, "", hdPath, hd.Secp256k1)
+		requireT.NoError(err)
+		address, err := info.GetAddress()
+		requireT.NoError(err)
+		requireT.Equal(address, key.address)
+	}
+
+	// every account is different
+	addresses := map[string]bool{}
+	for _, key := range keys {
+		addresses[key.address.String()] = true
+	}
+	requireT.Len(addresses, len(keys))
+}
+
+func TestParseConfigFundingAccounts(t *testing.T) {
+	parse := func(args ...string) (cfg, error) {
+		return parseConfig(pflag.NewFlagSet("faucet", pflag.ContinueOnError), args)
+	}
+
+	conf, err := parse()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, conf.fundingAccounts)
+
+	conf, err = parse("--" + flagFundingAccounts + "=" + strconv.Itoa(maxFundingAccounts))
+	require.NoError(t, err)
+	require.EqualValues(t, maxFundingAccounts, conf.fundingAccounts)
+
+	_, err = parse("--" + flagKeyringBackend + "=" + keyring.BackendTest)
+	require.NoError(t, err)
+
+	for _, args := range [][]string{
+		{"--" + flagFundingAccounts + "=0"},
+		{"--" + flagFundingAccounts + "=" + strconv.Itoa(maxFundingAccounts+1)},
+	} {
+		_, err := parse(args...)
+		require.Error(t, err, strings.Join(args, " "))
+	}
+
+	// keys are not derived from the keyring
+	_, err = parse("--"+flagFundingAccounts+"=2", "--"+flagKeyringBackend+"="+keyring.BackendTest)
+	require.ErrorContains(t, err, flagKeyringBackend)
+}
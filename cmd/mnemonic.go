@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+
+	"github.com/CoreumFoundation/faucet/pkg/mnemonic"
+)
+
+const (
+	// envPassphrase is the env var the passphrase of the encrypted mnemonic file is read from if the passphrase
+	// file is not set. It is deliberately not exposed as a flag, so the passphrase doesn't appear in the process list.
+	envPassphrase = "KEY_PASSPHRASE"
+
+	cmdEncryptMnemonic = "encrypt-mnemonic"
+	flagIn             = "in"
+	flagOut            = "out"
+)
+
+// readMnemonicFile returns the content of the mnemonic file, decrypting it if it is encrypted.
+func readMnemonicFile(path, passphraseFilePath string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read file at %s", path)
+	}
+	if !mnemonic.IsEncrypted(content) {
+		return content, nil
+	}
+
+	passphrase, err := readPassphrase(passphraseFilePath)
+	if err != nil {
+		return nil, err
+	}
+	content, err = mnemonic.Decrypt(content, passphrase)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to decrypt file at %s", path)
+	}
+	return content, nil
+}
+
+// readPassphrase reads the passphrase from the file, or from the env var if the file path is empty.
+func readPassphrase(filePath string) ([]byte, error) {
+	if filePath == "" {
+		passphrase := os.Getenv(envPassphrase)
+		if passphrase == "" {
+			return nil, errors.Errorf("passphrase is required, set --%s or %s env var",
+				flagPassphraseFilePath, envPassphrase)
+		}
+		return []byte(passphrase), nil
+	}
+
+	passphrase, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read passphrase file at %s", filePath)
+	}
+	passphrase = bytes.TrimRight(passphrase, "\r\n")
+	if len(passphrase) == 0 {
+		return nil, errors.Errorf("passphrase file at %s is empty", filePath)
+	}
+	return passphrase, nil
+}
+
+// encryptMnemonicCmd encrypts the plaintext mnemonic file, so it might be used as --key-path-mnemonic.
+func encryptMnemonicCmd(args []string) {
+	var in, out, passphraseFilePath string
+	flagSet := pflag.NewFlagSet(cmdEncryptMnemonic, pflag.ExitOnError)
+	flagSet.StringVar(&in, flagIn, "mnemonic.txt", "path to the plaintext mnemonic file")
+	flagSet.StringVar(&out, flagOut, "mnemonic.enc", "path to the encrypted file to be created")
+	flagSet.StringVar(&passphraseFilePath, flagPassphraseFilePath, "",
+		"path to file containing the passphrase, if not set the passphrase is read from "+envPassphrase+" env var")
+	_ = flagSet.Parse(args)
+
+	if err := encryptMnemonicFile(in, out, passphraseFilePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encrypting mnemonic file: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func encryptMnemonicFile(in, out, passphraseFilePath string) error {
+	passphrase, err := readPassphrase(passphraseFilePath)
+	if err != nil {
+		return err
+	}
+	plaintext, err := os.ReadFile(in)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read file at %s", in)
+	}
+	if mnemonic.IsEncrypted(plaintext) {
+		return errors.Errorf("file at %s is already encrypted", in)
+	}
+	encrypted, err := mnemonic.Encrypt(plaintext, passphrase)
+	if err != nil {
+		return err
+	}
+	// existing file is never overwritten to not lose the mnemonics by mistake
+	file, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create file at %s", out)
+	}
+	if _, err := file.Write(encrypted); err != nil {
+		_ = file.Close()
+		return errors.Wrapf(err, "unable to write file at %s", out)
+	}
+	return errors.WithStack(file.Close())
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+	"github.com/CoreumFoundation/coreum-tools/pkg/parallel"
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+	"github.com/CoreumFoundation/faucet/client/coreum"
+	"github.com/CoreumFoundation/faucet/pkg/signal"
+)
+
+// keyReloader reloads the funding keys from the mnemonic file without restarting the faucet, so the queued
+// requests are not dropped. Keys are reloaded on SIGHUP and once the file is modified.
+type keyReloader struct {
+	cfg      cfg
+	kr       keyring.Keyring
+	batcher  *coreum.Batcher
+	client   coreum.Client
+	treasury sdk.AccAddress
+	ctx      client.Context
+
+	// mu serializes the reloads with the removal of the retired keys, so the key of the account added back
+	// is never removed.
+	mu sync.Mutex
+	// digest is the hash of the file content the funding keys were loaded from.
+	digest [sha256.Size]byte
+}
+
+func newKeyReloader(
+	cfg cfg,
+	kr keyring.Keyring,
+	batcher *coreum.Batcher,
+	client coreum.Client,
+	treasury sdk.AccAddress,
+	ctx client.Context,
+) (*keyReloader, error) {
+	digest, err := fileDigest(cfg.mnemonicFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return &keyReloader{
+		cfg:      cfg,
+		kr:       kr,
+		batcher:  batcher,
+		client:   client,
+		treasury: treasury,
+		ctx:      ctx,
+		digest:   digest,
+	}, nil
+}
+
+// Run reloads the funding keys on SIGHUP or once the mnemonic file is modified.
+func (r *keyReloader) Run(ctx context.Context) error {
+	return parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
+		spawn("reload", parallel.Fail, func(ctx context.Context) error {
+			log := logger.Get(ctx)
+			reloadChan := signal.ReloadSignal(ctx)
+			var tickChan <-chan time.Time
+			if r.cfg.keyReloadInterval > 0 {
+				ticker := time.NewTicker(r.cfg.keyReloadInterval)
+				defer ticker.Stop()
+				tickChan = ticker.C
+			}
+
+			for {
+				var err error
+				select {
+				case <-ctx.Done():
+					return errors.WithStack(ctx.Err())
+				case <-reloadChan:
+					err = r.reload(ctx, spawn, true)
+				case <-tickChan:
+					err = r.reload(ctx, spawn, false)
+				}
+				if err != nil {
+					log.Error("Reloading funding keys failed, the current ones are kept", zap.Error(err))
+				}
+			}
+		})
+		return nil
+	})
+}
+
+// reload applies the keys read from the mnemonic file. If force is false, keys are reloaded only if the file
+// has been modified. New funding accounts are added first, so the batches are never left without a worker, then
+// the ones removed from the file are retired. Digest is updated only once the keys are applied, so the failed
+// reload is retried.
+func (r *keyReloader) reload(ctx context.Context, spawn parallel.SpawnFn, force bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	digest, err := fileDigest(r.cfg.mnemonicFilePath)
+	if err != nil {
+		return err
+	}
+	if !force && digest == r.digest {
+		return nil
+	}
+
+	keys, err := readFundingKeys(r.cfg.mnemonicFilePath, r.cfg.passphraseFilePath, r.cfg.fundingAccounts, r.ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.address.Equals(r.treasury) {
+			return errors.Errorf("treasury %s must not be a funding account", r.treasury)
+		}
+	}
+
+	log := logger.Get(ctx)
+	current := r.batcher.FundingAddresses()
+	for _, key := range keys {
+		if containsAddress(current, key.address) {
+			continue
+		}
+		// key is still in the keyring if the account was retired recently
+		if _, err := r.kr.Key(key.address.String()); err != nil {
+			if err := addKey(r.kr, key); err != nil {
+				return err
+			}
+		}
+		if err := r.batcher.AddFundingAccount(key.address); err != nil {
+			return err
+		}
+		log.Info("Funding account added", zap.Stringer("address", key.address))
+	}
+
+	for _, address := range current {
+		if containsKey(keys, address) {
+			continue
+		}
+		done, err := r.batcher.RetireFundingAccount(address)
+		if err != nil {
+			return err
+		}
+		log.Info("Retiring funding account", zap.Stringer("address", address))
+		spawn("removeKey-"+address.String(), parallel.Continue, func(ctx context.Context) error {
+			return r.removeKey(ctx, address, done)
+		})
+	}
+	r.digest = digest
+	return nil
+}
+
+// removeKey removes the key and the cached state of the retired funding account once its in-flight batches are
+// processed.
+func (r *keyReloader) removeKey(ctx context.Context, address sdk.AccAddress, done <-chan struct{}) error {
+	select {
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	case <-done:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// account might have been added back in the meantime
+	if !r.batcher.ForgetFundingAccount(address) {
+		return nil
+	}
+	r.client.ForgetAccount(address)
+	if err := r.kr.Delete(address.String()); err != nil {
+		logger.Get(ctx).Error("Unable to remove key of retired funding account",
+			zap.Stringer("address", address), zap.Error(err))
+		return nil
+	}
+	logger.Get(ctx).Info("Key of retired funding account removed", zap.Stringer("address", address))
+	return nil
+}
+
+func fileDigest(path string) ([sha256.Size]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, errors.Wrapf(err, "unable to read file at %s", path)
+	}
+	return sha256.Sum256(bytes.TrimSpace(content)), nil
+}
+
+func containsAddress(addresses []sdk.AccAddress, address sdk.AccAddress) bool {
+	for _, a := range addresses {
+		if a.Equals(address) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKey(keys []fundingKey, address sdk.AccAddress) bool {
+	for _, key := range keys {
+		if key.address.Equals(address) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
+	"github.com/CoreumFoundation/coreum-tools/pkg/parallel"
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config/constant"
+	"github.com/CoreumFoundation/faucet/client/coreum"
+)
+
+func TestKeyReloader(t *testing.T) {
+	requireT := require.New(t)
+
+	clientCtx := newTestClientContext()
+	mnemonic1, address1 := newTestMnemonic(t, clientCtx)
+	mnemonic2, address2 := newTestMnemonic(t, clientCtx)
+	mnemonic3, address3 := newTestMnemonic(t, clientCtx)
+	path := filepath.Join(t.TempDir(), "mnemonics")
+	writeMnemonics := func(mnemonics ...string) {
+		requireT.NoError(os.WriteFile(path, []byte(strings.Join(mnemonics, "\n")+"\n"), 0o600))
+	}
+	writeMnemonics(mnemonic1)
+
+	conf := cfg{mnemonicFilePath: path, fundingAccounts: 1}
+	kr, addresses, err := newKeyringFromFile(path, "", conf.fundingAccounts, clientCtx)
+	requireT.NoError(err)
+	network, err := config.NetworkConfigByChainID(constant.ChainIDDev)
+	requireT.NoError(err)
+	cl := coreum.New(network, nil, client.Factory{}, coreum.GasEstimator{})
+	// batcher is not started, so the retired accounts have no batches in flight
+	batcher := coreum.NewBatcher(cl, addresses, coreum.DefaultBatcherConfig())
+	r, err := newKeyReloader(conf, kr, batcher, cl, nil, clientCtx)
+	requireT.NoError(err)
+
+	ctx := logger.WithLogger(t.Context(), zaptest.NewLogger(t))
+	// reload returns once the keys of the retired accounts are removed
+	reload := func(force bool) error {
+		return parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
+			return r.reload(ctx, spawn, force)
+		})
+	}
+	requireKeys := func(expected ...sdk.AccAddress) {
+		requireT.ElementsMatch(expected, batcher.FundingAddresses())
+		records, err := kr.List()
+		requireT.NoError(err)
+		var keys []string
+		for _, record := range records {
+			keys = append(keys, record.Name)
+		}
+		var expectedKeys []string
+		for _, address := range expected {
+			expectedKeys = append(expectedKeys, address.String())
+		}
+		requireT.ElementsMatch(expectedKeys, keys)
+	}
+
+	// unmodified file is not reloaded
+	requireT.NoError(reload(false))
+	requireKeys(address1)
+
+	// account is added
+	writeMnemonics(mnemonic1, mnemonic2)
+	requireT.NoError(reload(false))
+	requireKeys(address1, address2)
+
+	// account is retired and its key removed
+	writeMnemonics(mnemonic2)
+	requireT.NoError(reload(false))
+	requireKeys(address2)
+
+	// retired account is added back
+	writeMnemonics(mnemonic1, mnemonic2)
+	requireT.NoError(reload(false))
+	requireKeys(address1, address2)
+
+	// key of the account added back before the retirement completed is kept
+	done := make(chan struct{})
+	close(done)
+	requireT.NoError(r.removeKey(ctx, address1, done))
+	requireKeys(address1, address2)
+
+	// digest is not updated if the keys can't be applied, so the reload is retried
+	digest := r.digest
+	r.treasury = address3
+	writeMnemonics(mnemonic1, mnemonic2, mnemonic3)
+	requireT.Error(reload(false))
+	requireT.Equal(digest, r.digest)
+	requireKeys(address1, address2)
+
+	r.treasury = nil
+	requireT.NoError(reload(false))
+	requireT.NotEqual(digest, r.digest)
+	requireKeys(address1, address2, address3)
+
+	// forced reload of the unmodified file keeps the keys
+	requireT.NoError(reload(true))
+	requireKeys(address1, address2, address3)
+}
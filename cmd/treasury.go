@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pkg/errors"
+
+	"github.com/CoreumFoundation/coreum/v5/pkg/client"
+)
+
+// addTreasuryKey adds the treasury key to the keyring holding the funding keys, so the treasury transfers are signed
+// the same way. Treasury is read from the keyring directory if it is used, otherwise from the first mnemonic
+// of the treasury mnemonic file.
+func addTreasuryKey(cfg cfg, kr keyring.Keyring, ctx client.Context) (sdk.AccAddress, error) {
+	if cfg.keyringBackend != "" {
+		if cfg.treasuryKeyName == "" {
+			return nil, errors.Errorf("--%s must be set to use the treasury with the keyring", flagTreasuryKeyName)
+		}
+		record, err := kr.Key(cfg.treasuryKeyName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get treasury key %q", cfg.treasuryKeyName)
+		}
+		address, err := record.GetAddress()
+		return address, errors.Wrapf(err, "unable to get address of treasury key %q", cfg.treasuryKeyName)
+	}
+
+	if cfg.treasuryMnemonicPath == "" {
+		return nil, errors.Errorf("--%s must be set to use the treasury", flagTreasuryMnemonicPath)
+	}
+	content, err := readMnemonicFile(cfg.treasuryMnemonicPath, cfg.passphraseFilePath)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if mnemonic := strings.TrimSpace(scanner.Text()); mnemonic != "" {
+			return addMnemonicKey(kr, mnemonic, 0, ctx)
+		}
+	}
+	return nil, errors.Errorf("no mnemonic in file at %s", cfg.treasuryMnemonicPath)
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"crypto/subtle"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/CoreumFoundation/faucet/client/coreum"
+	"github.com/CoreumFoundation/faucet/pkg/http"
+)
+
+// Errors returned when authenticating the request.
+var (
+	// ErrInvalidAPIKey is returned when the request carries an API key which is not known.
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrInternalKeyRequired is returned when the endpoint is called without the internal API key.
+	ErrInternalKeyRequired = errors.New("internal API key is required")
+)
+
+const bearerPrefix = "Bearer "
+
+// APIKeys are the API keys and the priorities of the requests sent with them. Keys might be replaced while running.
+type APIKeys struct {
+	mu   sync.RWMutex
+	keys map[string]coreum.Priority
+}
+
+// NewAPIKeys returns new instance of APIKeys.
+func NewAPIKeys(keys map[string]coreum.Priority) *APIKeys {
+	return &APIKeys{keys: keys}
+}
+
+// Set replaces the API keys.
+func (k *APIKeys) Set(keys map[string]coreum.Priority) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = keys
+}
+
+func (k *APIKeys) priority(key string) (coreum.Priority, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return priorityForKey(k.keys, key)
+}
+
+// priorityMiddleware sets the priority of the fund requests sent with a known API key. Requests without
+// the key are processed with public priority.
+func priorityMiddleware(apiKeys *APIKeys) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(c http.Context) error {
+			r := c.Request()
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				return next(c)
+			}
+			if !strings.HasPrefix(header, bearerPrefix) {
+				return errors.Wrap(ErrInvalidAPIKey, "bearer token expected")
+			}
+
+			priority, ok := apiKeys.priority(strings.TrimPrefix(header, bearerPrefix))
+			if !ok {
+				return errors.WithStack(ErrInvalidAPIKey)
+			}
+			c.SetRequest(r.WithContext(coreum.WithPriority(r.Context(), priority)))
+			return next(c)
+		}
+	}
+}
+
+// internalOnlyMiddleware rejects the requests not sent with the internal API key. It relies on the priority set
+// by priorityMiddleware.
+func internalOnlyMiddleware() func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(c http.Context) error {
+			if coreum.PriorityFromContext(c.Request().Context()) != coreum.PriorityInternal {
+				return errors.WithStack(ErrInternalKeyRequired)
+			}
+			return next(c)
+		}
+	}
+}
+
+func priorityForKey(apiKeys map[string]coreum.Priority, key string) (coreum.Priority, bool) {
+	for k, priority := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return priority, true
+		}
+	}
+	return 0, false
+}
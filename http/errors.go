@@ -3,6 +3,8 @@ package http
 import (
 	"encoding/json"
 	nethttp "net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
@@ -11,11 +13,16 @@ import (
 	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
 	"github.com/CoreumFoundation/faucet/app"
 	"github.com/CoreumFoundation/faucet/pkg/http"
+	"github.com/CoreumFoundation/faucet/pkg/idempotency"
 )
 
 // ErrRateLimitExhausted is returned when rate limit is exhausted for an IP address.
 var ErrRateLimitExhausted = errors.New("rate limit exhausted")
 
+// queueFullRetryAfter is the delay suggested to the client when the request queue is full or the faucet is
+// shutting down.
+const queueFullRetryAfter = 5 * time.Second
+
 func writeErrorMiddleware() func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(c http.Context) error {
@@ -30,6 +37,10 @@ func writeErrorMiddleware() func(http.HandlerFunc) http.HandlerFunc {
 				if mappedError.Loggable() {
 					logger.Get(c.Request().Context()).Error("Error processing request", zap.Error(err))
 				}
+				if errors.Is(err, app.ErrQueueFull) || errors.Is(err, app.ErrShuttingDown) ||
+					errors.Is(err, app.ErrNoFundingAccount) {
+					c.Response().Header().Set("Retry-After", strconv.Itoa(int(queueFullRetryAfter.Seconds())))
+				}
 
 				return c.JSON(mappedError.Status(), mappedError)
 			}
@@ -105,10 +116,38 @@ func mapError(err error) APIError {
 			nethttp.StatusUnprocessableEntity, false),
 		app.ErrInvalidAddressFormat: newSingleAPIError("address.invalid", app.ErrInvalidAddressFormat.Error(),
 			nethttp.StatusUnprocessableEntity, false),
+		app.ErrDenomNotAllowed: newSingleAPIError("denom.not_allowed", app.ErrDenomNotAllowed.Error(),
+			nethttp.StatusUnprocessableEntity, false),
+		app.ErrInvalidAmount: newSingleAPIError("amount.invalid", app.ErrInvalidAmount.Error(),
+			nethttp.StatusUnprocessableEntity, false),
+		app.ErrAmountExceedsLimit: newSingleAPIError("amount.exceeds_limit", app.ErrAmountExceedsLimit.Error(),
+			nethttp.StatusUnprocessableEntity, false),
+		app.ErrInvalidHistoryQuery: newSingleAPIError("query.invalid", app.ErrInvalidHistoryQuery.Error(),
+			nethttp.StatusBadRequest, false),
+		app.ErrJobNotFound: newSingleAPIError("job.not_found", app.ErrJobNotFound.Error(),
+			nethttp.StatusNotFound, false),
+		app.ErrDuplicateRequest: newSingleAPIError("request.duplicate", app.ErrDuplicateRequest.Error(),
+			nethttp.StatusConflict, false),
+		app.ErrQueueFull: newSingleAPIError("server.queue_full", app.ErrQueueFull.Error(),
+			nethttp.StatusServiceUnavailable, false),
+		app.ErrShuttingDown: newSingleAPIError("server.shutting_down", app.ErrShuttingDown.Error(),
+			nethttp.StatusServiceUnavailable, false),
+		app.ErrNoFundingAccount: newSingleAPIError("server.no_funding_account", app.ErrNoFundingAccount.Error(),
+			nethttp.StatusServiceUnavailable, false),
+		app.ErrRequestCanceled: newSingleAPIError("request.canceled", app.ErrRequestCanceled.Error(),
+			nethttp.StatusRequestTimeout, false),
 		app.ErrUnableToTransferToken: newSingleAPIError("server.internal_error", app.ErrUnableToTransferToken.Error(),
 			nethttp.StatusInternalServerError, true),
+		ErrInvalidAPIKey: newSingleAPIError("auth.invalid_key", ErrInvalidAPIKey.Error(),
+			nethttp.StatusUnauthorized, false),
+		ErrInternalKeyRequired: newSingleAPIError("auth.forbidden", ErrInternalKeyRequired.Error(),
+			nethttp.StatusForbidden, false),
+		idempotency.ErrKeyReused: newSingleAPIError("idempotency.key_reused", idempotency.ErrKeyReused.Error(),
+			nethttp.StatusUnprocessableEntity, false),
 		ErrRateLimitExhausted: newSingleAPIError("server.rate_limit", ErrRateLimitExhausted.Error(),
 			nethttp.StatusTooManyRequests, false),
+		app.ErrAddressRateLimited: newSingleAPIError("server.rate_limit_address",
+			app.ErrAddressRateLimited.Error(), nethttp.StatusTooManyRequests, false),
 	}
 
 	for e, internalErr := range errList {
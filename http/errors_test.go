@@ -0,0 +1,56 @@
+package http
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CoreumFoundation/faucet/app"
+)
+
+func TestMapError(t *testing.T) {
+	testCases := []struct {
+		err            error
+		expectedKind   string
+		expectedStatus int
+	}{
+		{
+			err:            errors.Wrap(app.ErrAddressRateLimited, "address has already used its rate limit"),
+			expectedKind:   "server.rate_limit_address",
+			expectedStatus: nethttp.StatusTooManyRequests,
+		},
+		{
+			err:            errors.Wrap(ErrRateLimitExhausted, "ip has already used its rate limit"),
+			expectedKind:   "server.rate_limit",
+			expectedStatus: nethttp.StatusTooManyRequests,
+		},
+		{
+			err:            errors.New("unexpected"),
+			expectedKind:   "server.internal_error",
+			expectedStatus: nethttp.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expectedKind, func(t *testing.T) {
+			requireT := require.New(t)
+
+			apiErr := mapError(tc.err)
+			requireT.Equal(tc.expectedStatus, apiErr.Status())
+
+			body, err := json.Marshal(apiErr)
+			requireT.NoError(err)
+			var resp struct {
+				Content []struct {
+					Kind string `json:"kind"`
+				} `json:"content"`
+			}
+			requireT.NoError(json.Unmarshal(body, &resp))
+			requireT.Len(resp.Content, 1)
+			requireT.Equal(tc.expectedKind, resp.Content[0].Kind)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	nethttp "net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/CoreumFoundation/faucet/app"
+	"github.com/CoreumFoundation/faucet/pkg/http"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+)
+
+const defaultHistoryLimit = 50
+
+// HistoryRecord is a single fund request returned by the /history and /jobs/{id} requests.
+type HistoryRecord struct {
+	ID             string    `json:"id"`
+	RequestID      string    `json:"requestId"`
+	Address        string    `json:"address"`
+	Coins          string    `json:"coins"`
+	FundingAddress string    `json:"fundingAddress,omitempty"`
+	TxHash         string    `json:"txHash,omitempty"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// HistoryResponse is the output to /history request.
+type HistoryResponse struct {
+	Records    []HistoryRecord `json:"records"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+func (h HTTP) historyHandle(ctx http.Context) error {
+	filter := ledger.Filter{
+		Address: ctx.QueryParam("address"),
+		TxHash:  ctx.QueryParam("txHash"),
+		Status:  ledger.Status(ctx.QueryParam("status")),
+	}
+	var err error
+	if filter.From, err = parseTimeParam(ctx, "from"); err != nil {
+		return err
+	}
+	if filter.To, err = parseTimeParam(ctx, "to"); err != nil {
+		return err
+	}
+
+	limit := defaultHistoryLimit
+	if limitParam := ctx.QueryParam("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil {
+			return errors.Wrapf(app.ErrInvalidHistoryQuery, "invalid limit %q", limitParam)
+		}
+	}
+
+	records, nextCursor, err := h.app.History(filter, ctx.QueryParam("cursor"), limit)
+	if err != nil {
+		return err
+	}
+
+	resp := HistoryResponse{
+		Records:    make([]HistoryRecord, 0, len(records)),
+		NextCursor: nextCursor,
+	}
+	for _, record := range records {
+		resp.Records = append(resp.Records, newHistoryRecord(record))
+	}
+
+	return ctx.JSON(nethttp.StatusOK, resp)
+}
+
+func (h HTTP) jobHandle(ctx http.Context) error {
+	record, err := h.app.Job(ctx.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(nethttp.StatusOK, newHistoryRecord(record))
+}
+
+func newHistoryRecord(record ledger.Record) HistoryRecord {
+	return HistoryRecord{
+		ID:             record.ID,
+		RequestID:      record.RequestID,
+		Address:        record.Address,
+		Coins:          record.Coins,
+		FundingAddress: record.FundingAddress,
+		TxHash:         record.TxHash,
+		Status:         string(record.Status),
+		Error:          record.Error,
+		CreatedAt:      record.CreatedAt,
+		UpdatedAt:      record.UpdatedAt,
+	}
+}
+
+func parseTimeParam(ctx http.Context, name string) (time.Time, error) {
+	value := ctx.QueryParam(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(app.ErrInvalidHistoryQuery, "%s must be RFC3339 time, got %q", name, value)
+	}
+	return t, nil
+}
@@ -10,7 +10,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/CoreumFoundation/faucet/app"
+	"github.com/CoreumFoundation/faucet/client/coreum"
 	"github.com/CoreumFoundation/faucet/pkg/http"
+	"github.com/CoreumFoundation/faucet/pkg/idempotency"
 	"github.com/CoreumFoundation/faucet/pkg/limiter"
 )
 
@@ -20,16 +22,34 @@ type HTTP struct {
 	server http.Server
 }
 
-// New returns an instance of the HTTP type.
-func New(app app.App, limiter limiter.PerIPLimiter, log *zap.Logger) HTTP {
+// New returns an instance of the HTTP type. Fund requests sent with one of the apiKeys are processed
+// with the priority assigned to the key.
+func New(
+	app app.App,
+	limiter limiter.PerIPLimiter,
+	idempotencyCache *idempotency.Cache,
+	apiKeys *APIKeys,
+	log *zap.Logger,
+) HTTP {
 	return HTTP{
-		app:    app,
-		server: http.New(log, writeErrorMiddleware(), limiterMiddleware(limiter)),
+		app: app,
+		server: http.New(
+			log,
+			writeErrorMiddleware(),
+			priorityMiddleware(apiKeys),
+			idempotencyMiddleware(idempotencyCache),
+			limiterMiddleware(limiter),
+		),
 	}
 }
 
 // ListenAndServe starts listening for http requests.
 func (h HTTP) ListenAndServe(ctx context.Context, address string) error {
+	h.registerRoutes()
+	return h.server.Start(ctx, address, 30*time.Second)
+}
+
+func (h HTTP) registerRoutes() {
 	apiv1 := h.server.Group(
 		"/api/faucet/v1",
 		middleware.BodyLimit("4MB"),
@@ -38,28 +58,58 @@ func (h HTTP) ListenAndServe(ctx context.Context, address string) error {
 	apiv1.GET("/status", h.statusHandle)
 	apiv1.POST("/fund", h.fundHandle)
 	apiv1.POST("/gen-funded", h.genFundedHandle)
-
-	return h.server.Start(ctx, address, 30*time.Second)
+	// history exposes the destination addresses and the errors of all the requests, so it is internal only
+	apiv1.GET("/history", h.historyHandle, internalOnlyMiddleware())
+	apiv1.GET("/jobs/:id", h.jobHandle)
 }
 
-// StatusResponse is the output to /status request.
+// StatusResponse is the output to /status request. Details of the nodes are returned only to the callers
+// with the internal API key, as they expose the internal node addresses.
 type StatusResponse struct {
-	Version string `json:"version"`
-	Status  string `json:"status"`
-	Go      string `json:"go"`
+	Version string       `json:"version"`
+	Status  string       `json:"status"`
+	Go      string       `json:"go"`
+	Healthy bool         `json:"healthy"`
+	Node    string       `json:"node,omitempty"`
+	Nodes   []NodeStatus `json:"nodes,omitempty"`
+}
+
+// NodeStatus is the state of the coreum node returned by /status request.
+type NodeStatus struct {
+	URL     string `json:"url"`
+	Active  bool   `json:"active"`
+	Healthy bool   `json:"healthy"`
+	Syncing bool   `json:"syncing"`
+	Height  int64  `json:"height"`
+	Error   string `json:"error,omitempty"`
 }
 
 func (h HTTP) statusHandle(ctx http.Context) error {
-	return ctx.JSON(nethttp.StatusOK, StatusResponse{
+	resp := StatusResponse{
 		Version: "v1.0.0",
 		Status:  "listening",
 		Go:      runtime.Version(),
-	})
+	}
+	internal := coreum.PriorityFromContext(ctx.Request().Context()) == coreum.PriorityInternal
+	for _, node := range h.app.Nodes() {
+		resp.Healthy = resp.Healthy || node.Healthy
+		if !internal {
+			continue
+		}
+		if node.Active {
+			resp.Node = node.URL
+		}
+		resp.Nodes = append(resp.Nodes, NodeStatus(node))
+	}
+
+	return ctx.JSON(nethttp.StatusOK, resp)
 }
 
 // FundRequest is the input to GiveFunds request.
 type FundRequest struct {
 	Address string `json:"address"`
+	Denom   string `json:"denom,omitempty"`
+	Amount  string `json:"amount,omitempty"`
 }
 
 // FundResponse is the output to GiveFunds request.
@@ -67,13 +117,27 @@ type FundResponse struct {
 	TxHash string `json:"txHash"`
 }
 
+// FundAsyncResponse is the output to GiveFunds request executed in async mode.
+type FundAsyncResponse struct {
+	JobID string `json:"jobId"`
+}
+
 func (h HTTP) fundHandle(ctx http.Context) error {
 	var rqBody FundRequest
 	if err := ctx.Bind(&rqBody); err != nil {
 		return err
 	}
 
-	txHash, err := h.app.GiveFunds(ctx.Request().Context(), rqBody.Address)
+	if ctx.QueryParam("async") == "true" {
+		jobID, err := h.app.GiveFundsAsync(ctx.Request().Context(), rqBody.Address, rqBody.Denom, rqBody.Amount)
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(nethttp.StatusAccepted, FundAsyncResponse{JobID: jobID})
+	}
+
+	txHash, err := h.app.GiveFunds(ctx.Request().Context(), rqBody.Address, rqBody.Denom, rqBody.Amount)
 	if err != nil {
 		return err
 	}
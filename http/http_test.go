@@ -0,0 +1,191 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	coreumclient "github.com/CoreumFoundation/coreum/v5/pkg/client"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config"
+	"github.com/CoreumFoundation/coreum/v5/pkg/config/constant"
+	"github.com/CoreumFoundation/faucet/app"
+	"github.com/CoreumFoundation/faucet/client/coreum"
+	"github.com/CoreumFoundation/faucet/pkg/idempotency"
+	"github.com/CoreumFoundation/faucet/pkg/ledger"
+)
+
+const (
+	internalKey      = "internal-key"
+	authenticatedKey = "authenticated-key"
+)
+
+// mockBatcher executes every transfer successfully, returning the tx hash numbered by the order of the transfer.
+// If release is set, transfers wait until it is closed and started is notified when they do.
+type mockBatcher struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (b *mockBatcher) SendTokenAsync(
+	_ context.Context,
+	_ sdk.AccAddress,
+	_ sdk.Coins,
+	register coreum.RegisterFunc,
+	done coreum.DoneFunc,
+) error {
+	if _, err := register(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.calls++
+	txHash := fmt.Sprintf("tx-hash-%d", b.calls)
+	b.mu.Unlock()
+
+	go func() {
+		if b.release != nil {
+			b.started <- struct{}{}
+			<-b.release
+		}
+		done(coreum.TransferResult{TxHash: txHash}, nil)
+	}()
+	return nil
+}
+
+func (b *mockBatcher) Calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.calls
+}
+
+type mockNodes []coreum.NodeStatus
+
+func (m mockNodes) ClientContext() coreumclient.Context {
+	return coreumclient.Context{}
+}
+
+func (m mockNodes) Statuses() []coreum.NodeStatus {
+	return m
+}
+
+type noIPLimit struct{}
+
+func (noIPLimit) Reserve(net.IP) bool { return true }
+func (noIPLimit) Refund(net.IP)       {}
+
+type noAddressLimit struct{}
+
+func (noAddressLimit) Reserve(string) bool { return true }
+func (noAddressLimit) Refund(string)       {}
+
+func newTestHTTP(t *testing.T, batcher app.Batcher, nodes mockNodes) HTTP {
+	requireT := require.New(t)
+
+	network, err := config.NetworkConfigByChainID(constant.ChainIDDev)
+	requireT.NoError(err)
+	store, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.db"))
+	requireT.NoError(err)
+	t.Cleanup(func() {
+		requireT.NoError(store.Close())
+	})
+
+	amount := sdk.NewCoins(sdk.NewInt64Coin(network.Denom(), 100))
+	h := New(
+		app.New(
+			coreumclient.NewContext(coreumclient.DefaultContextConfig(), auth.AppModuleBasic{}),
+			batcher,
+			store,
+			nodes,
+			network,
+			amount,
+			amount,
+			noAddressLimit{},
+		),
+		noIPLimit{},
+		idempotency.NewCache(time.Hour),
+		NewAPIKeys(map[string]coreum.Priority{
+			internalKey:      coreum.PriorityInternal,
+			authenticatedKey: coreum.PriorityAuthenticated,
+		}),
+		zaptest.NewLogger(t),
+	)
+	h.registerRoutes()
+	return h
+}
+
+// newRequest returns the request sent from the ip, authenticated with the apiKey if it is not empty.
+func newRequest(method, path, body, ip, apiKey string) *nethttp.Request {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.RemoteAddr = net.JoinHostPort(ip, "1234")
+	r.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		r.Header.Set("Authorization", bearerPrefix+apiKey)
+	}
+	return r
+}
+
+func serve(h HTTP, r *nethttp.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.server.ServeHTTP(w, r)
+	return w
+}
+
+func testAddress(t *testing.T) string {
+	address, err := bech32.ConvertAndEncode(constant.AddressPrefixDev, make([]byte, 20))
+	require.NoError(t, err)
+	return address
+}
+
+func TestStatusHandle(t *testing.T) {
+	requireT := require.New(t)
+
+	h := newTestHTTP(t, &mockBatcher{}, mockNodes{
+		{URL: "node-1:9090", Healthy: false, Error: "connection refused"},
+		{URL: "node-2:9090", Active: true, Healthy: true, Height: 100},
+	})
+	status := func(apiKey string) StatusResponse {
+		w := serve(h, newRequest(nethttp.MethodGet, "/api/faucet/v1/status", "", "203.0.113.1", apiKey))
+		requireT.Equal(nethttp.StatusOK, w.Code, w.Body.String())
+		var resp StatusResponse
+		requireT.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// node addresses are not exposed to the public callers
+	for _, apiKey := range []string{"", authenticatedKey} {
+		resp := status(apiKey)
+		requireT.True(resp.Healthy)
+		requireT.Empty(resp.Node)
+		requireT.Empty(resp.Nodes)
+	}
+
+	resp := status(internalKey)
+	requireT.True(resp.Healthy)
+	requireT.Equal("node-2:9090", resp.Node)
+	requireT.Equal([]NodeStatus{
+		{URL: "node-1:9090", Error: "connection refused"},
+		{URL: "node-2:9090", Active: true, Healthy: true, Height: 100},
+	}, resp.Nodes)
+
+	// status is unhealthy if none of the nodes is healthy
+	h = newTestHTTP(t, &mockBatcher{}, mockNodes{{URL: "node-1:9090", Active: true}})
+	requireT.False(status("").Healthy)
+}
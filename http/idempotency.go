@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	nethttp "net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/CoreumFoundation/faucet/app"
+	"github.com/CoreumFoundation/faucet/pkg/http"
+	"github.com/CoreumFoundation/faucet/pkg/idempotency"
+)
+
+// Idempotency headers.
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// maxIdempotentBodySize is the maximum size of the body of the request sent with the idempotency key.
+const maxIdempotentBodySize = 4 << 20
+
+// idempotencyMiddleware replays the stored response if POST request with the same idempotency key has been already
+// handled. It must be placed before the limiter middleware, so the replayed requests don't consume rate limit.
+// Keys are scoped to the API key of the caller or to its IP if the request is anonymous, so the responses are never
+// replayed to other callers. Key reused with a different request is rejected.
+func idempotencyMiddleware(cache *idempotency.Cache) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(c http.Context) error {
+			r := c.Request()
+			key := r.Header.Get(HeaderIdempotencyKey)
+			if r.Method != nethttp.MethodPost || key == "" {
+				return next(c)
+			}
+
+			scope, err := callerScope(r)
+			if err != nil {
+				return err
+			}
+			fingerprint, err := requestFingerprint(c)
+			if err != nil {
+				return err
+			}
+
+			resp, replayed, err := cache.Do(r.Context(), scope+":"+r.URL.Path+":"+key, fingerprint,
+				func() (idempotency.Response, error) {
+					writer := c.Response().Writer
+					recorder := &responseRecorder{ResponseWriter: writer}
+					c.Response().Writer = recorder
+					defer func() {
+						c.Response().Writer = writer
+					}()
+
+					if err := next(c); err != nil {
+						return idempotency.Response{}, err
+					}
+					return idempotency.Response{
+						Status:      c.Response().Status,
+						ContentType: c.Response().Header().Get(echo.HeaderContentType),
+						Body:        recorder.body.Bytes(),
+					}, nil
+				}, isRetryableError)
+			if replayed {
+				c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+			}
+			if err != nil || !replayed {
+				return err
+			}
+			return c.Blob(resp.Status, resp.ContentType, resp.Body)
+		}
+	}
+}
+
+// retryableErrors are known to be returned before the transfer is sent, so the request with the same idempotency
+// key is executed again. Any other error, e.g. the unknown outcome of the broadcast tx or the canceled request which
+// might still be sent, is replayed, so the transfer is never sent twice.
+var retryableErrors = []error{
+	app.ErrInvalidAddressFormat,
+	app.ErrAddressPrefixUnsupported,
+	app.ErrDenomNotAllowed,
+	app.ErrInvalidAmount,
+	app.ErrAmountExceedsLimit,
+	app.ErrAddressRateLimited,
+	app.ErrDuplicateRequest,
+	app.ErrQueueFull,
+	app.ErrShuttingDown,
+	app.ErrNoFundingAccount,
+	ErrRateLimitExhausted,
+}
+
+func isRetryableError(err error) bool {
+	// errors of echo are returned before the handler is executed, e.g. if the body can't be parsed
+	var echoErr *echo.HTTPError
+	if errors.As(err, &echoErr) {
+		return true
+	}
+	for _, retryableErr := range retryableErrors {
+		if errors.Is(err, retryableErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// callerScope identifies the caller by its API key, verified by priorityMiddleware, or by its IP.
+func callerScope(r *nethttp.Request) (string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		hash := sha256.Sum256([]byte(header))
+		return "key:" + hex.EncodeToString(hash[:]), nil
+	}
+	ip, err := http.IPFromRequest(r)
+	if err != nil {
+		return "", err
+	}
+	return "ip:" + ip.String(), nil
+}
+
+// requestFingerprint returns the hash of the query and the body of the request. Body is restored, so it might be
+// read by the handler.
+func requestFingerprint(c http.Context) (string, error) {
+	r := c.Request()
+	body, err := io.ReadAll(nethttp.MaxBytesReader(c.Response(), r.Body, maxIdempotentBodySize))
+	if err != nil {
+		var maxBytesErr *nethttp.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return "", echo.ErrStatusRequestEntityTooLarge
+		}
+		return "", errors.WithStack(err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	hash := sha256.New()
+	hash.Write([]byte(r.URL.RawQuery))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// responseRecorder copies the response body written by the handler.
+type responseRecorder struct {
+	nethttp.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
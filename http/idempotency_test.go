@@ -0,0 +1,131 @@
+package http
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyScopedByCaller(t *testing.T) {
+	requireT := require.New(t)
+
+	batcher := &mockBatcher{}
+	h := newTestHTTP(t, batcher, nil)
+	body := `{"address":"` + testAddress(t) + `"}`
+	fund := func(ip, apiKey string) *httptest.ResponseRecorder {
+		r := newRequest(nethttp.MethodPost, "/api/faucet/v1/fund", body, ip, apiKey)
+		r.Header.Set(HeaderIdempotencyKey, "key")
+		return serve(h, r)
+	}
+
+	first := fund("203.0.113.1", "")
+	requireT.Equal(nethttp.StatusOK, first.Code, first.Body.String())
+	requireT.Empty(first.Header().Get(HeaderIdempotentReplayed))
+
+	// the same anonymous caller gets the stored response
+	replayed := fund("203.0.113.1", "")
+	requireT.Equal(nethttp.StatusOK, replayed.Code)
+	requireT.Equal("true", replayed.Header().Get(HeaderIdempotentReplayed))
+	requireT.Equal(first.Body.String(), replayed.Body.String())
+
+	// anonymous caller with a different IP doesn't share the key
+	other := fund("203.0.113.2", "")
+	requireT.Equal(nethttp.StatusOK, other.Code)
+	requireT.Empty(other.Header().Get(HeaderIdempotentReplayed))
+	requireT.NotEqual(first.Body.String(), other.Body.String())
+
+	// caller with the API key is identified by the key, regardless of its IP
+	keyed := fund("203.0.113.1", authenticatedKey)
+	requireT.Equal(nethttp.StatusOK, keyed.Code)
+	requireT.Empty(keyed.Header().Get(HeaderIdempotentReplayed))
+	keyedReplayed := fund("203.0.113.3", authenticatedKey)
+	requireT.Equal("true", keyedReplayed.Header().Get(HeaderIdempotentReplayed))
+	requireT.Equal(keyed.Body.String(), keyedReplayed.Body.String())
+
+	requireT.Equal(3, batcher.Calls())
+}
+
+func TestIdempotencyReplaysResponses(t *testing.T) {
+	requireT := require.New(t)
+
+	batcher := &mockBatcher{}
+	h := newTestHTTP(t, batcher, nil)
+	send := func(path, body string) *httptest.ResponseRecorder {
+		r := newRequest(nethttp.MethodPost, path, body, "203.0.113.1", "")
+		r.Header.Set(HeaderIdempotencyKey, "key")
+		return serve(h, r)
+	}
+	body := `{"address":"` + testAddress(t) + `"}`
+
+	fund := send("/api/faucet/v1/fund", body)
+	requireT.Equal(nethttp.StatusOK, fund.Code, fund.Body.String())
+	fundReplayed := send("/api/faucet/v1/fund", body)
+	requireT.Equal(nethttp.StatusOK, fundReplayed.Code)
+	requireT.Equal("true", fundReplayed.Header().Get(HeaderIdempotentReplayed))
+	requireT.Equal(fund.Header().Get("Content-Type"), fundReplayed.Header().Get("Content-Type"))
+	var fundResp, fundReplayedResp FundResponse
+	requireT.NoError(json.Unmarshal(fund.Body.Bytes(), &fundResp))
+	requireT.NoError(json.Unmarshal(fundReplayed.Body.Bytes(), &fundReplayedResp))
+	requireT.Equal("tx-hash-1", fundResp.TxHash)
+	requireT.Equal(fundResp, fundReplayedResp)
+
+	// the same key used for another endpoint is stored separately
+	genFunded := send("/api/faucet/v1/gen-funded", "")
+	requireT.Equal(nethttp.StatusOK, genFunded.Code, genFunded.Body.String())
+	requireT.Empty(genFunded.Header().Get(HeaderIdempotentReplayed))
+	genFundedReplayed := send("/api/faucet/v1/gen-funded", "")
+	requireT.Equal(nethttp.StatusOK, genFundedReplayed.Code)
+	requireT.Equal("true", genFundedReplayed.Header().Get(HeaderIdempotentReplayed))
+	var genFundedResp, genFundedReplayedResp GenFundedResponse
+	requireT.NoError(json.Unmarshal(genFunded.Body.Bytes(), &genFundedResp))
+	requireT.NoError(json.Unmarshal(genFundedReplayed.Body.Bytes(), &genFundedReplayedResp))
+	requireT.Equal("tx-hash-2", genFundedResp.TxHash)
+	requireT.NotEmpty(genFundedResp.Mnemonic)
+	requireT.Equal(genFundedResp, genFundedReplayedResp)
+
+	// key reused with a different request is rejected
+	reused := send("/api/faucet/v1/fund", `{"address":"`+testAddress(t)+`","amount":"1"}`)
+	requireT.Equal(nethttp.StatusUnprocessableEntity, reused.Code)
+	requireT.Contains(reused.Body.String(), "idempotency.key_reused")
+
+	requireT.Equal(2, batcher.Calls())
+}
+
+func TestIdempotencyWaitsForInFlightRequest(t *testing.T) {
+	requireT := require.New(t)
+
+	batcher := &mockBatcher{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	h := newTestHTTP(t, batcher, nil)
+	body := `{"address":"` + testAddress(t) + `"}`
+	responses := make(chan *httptest.ResponseRecorder, 2)
+	fund := func() {
+		r := newRequest(nethttp.MethodPost, "/api/faucet/v1/fund", body, "203.0.113.1", "")
+		r.Header.Set(HeaderIdempotencyKey, "key")
+		responses <- serve(h, r)
+	}
+
+	go fund()
+	<-batcher.started
+	// the second request waits until the first one completes instead of sending the funds again
+	go fund()
+	requireT.Never(func() bool {
+		return len(responses) > 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	close(batcher.release)
+	first, second := <-responses, <-responses
+	requireT.Equal(nethttp.StatusOK, first.Code, first.Body.String())
+	requireT.Equal(first.Body.String(), second.Body.String())
+	requireT.ElementsMatch([]string{"", "true"}, []string{
+		first.Header().Get(HeaderIdempotentReplayed),
+		second.Header().Get(HeaderIdempotentReplayed),
+	})
+	requireT.Equal(1, batcher.Calls())
+}
@@ -21,12 +21,17 @@ func limiterMiddleware(limiter limiter.PerIPLimiter) func(http.HandlerFunc) http
 			if err != nil {
 				return err
 			}
-			if !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !limiter.IsRequestAllowed(ip) {
+			if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+				return next(c)
+			}
+			// rate limit is reserved before the request is handled, so the concurrent requests can't exceed it,
+			// and it is given back if the request fails
+			if !limiter.Reserve(ip) {
 				return errors.Wrapf(ErrRateLimitExhausted, "ip %q has already used its rate limit", ip.String())
 			}
 			err = next(c)
-			if err == nil {
-				limiter.Increment(ip)
+			if err != nil {
+				limiter.Refund(ip)
 			}
 
 			return err
@@ -7,6 +7,7 @@ import (
 	"github.com/cosmos/cosmos-sdk/types/module"
 	"github.com/cosmos/cosmos-sdk/x/auth"
 	"github.com/cosmos/cosmos-sdk/x/bank"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
@@ -32,15 +33,9 @@ func WithEnv(f *pflag.FlagSet, prefix string) error {
 			continue
 		}
 
-		name := flag.Name
-		if prefix != "" {
-			name = prefix + "_" + name
-		}
-		name = strings.ReplaceAll(strings.ToUpper(name), "-", "_")
-		envValue := os.Getenv(name)
+		envValue := os.Getenv(EnvName(prefix, flag.Name))
 		if envValue != "" {
-			flag.DefValue = envValue
-			if err := flag.Value.Set(envValue); err != nil {
+			if err := setValue(flag, envValue); err != nil {
 				return err
 			}
 		}
@@ -48,3 +43,24 @@ func WithEnv(f *pflag.FlagSet, prefix string) error {
 
 	return nil
 }
+
+// setValue replaces the value of the flag. Slice flags append the values on each Set after the first one, so they
+// are reset first, otherwise the list set in the file would be merged with the one set in the env var.
+func setValue(flag *pflag.Flag, value string) error {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		if err := slice.Replace(nil); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	flag.DefValue = value
+	return flag.Value.Set(value)
+}
+
+// EnvName returns the name of the env var the value of the flag is read from.
+func EnvName(prefix, flagName string) string {
+	name := flagName
+	if prefix != "" {
+		name = prefix + "_" + name
+	}
+	return strings.ReplaceAll(strings.ToUpper(name), "-", "_")
+}
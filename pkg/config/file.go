@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the values of the secret flags when the config is printed.
+const redacted = "<redacted>"
+
+// WithFile sets the values of the flags not set on the command line from the YAML or TOML file, the format is chosen
+// by the file extension. Keys of the file are the flag names, error is returned if the file contains unknown key.
+// It should be called before WithEnv, so the env vars take precedence over the file.
+func WithFile(f *pflag.FlagSet, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read config file at %s", path)
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	case ".toml":
+		err = toml.Unmarshal(content, &values)
+	default:
+		return errors.Errorf("unsupported format of config file %s, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse config file at %s", path)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var unknown []string
+	for _, key := range keys {
+		flag := f.Lookup(key)
+		if flag == nil {
+			unknown = append(unknown, key)
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+		value, err := fileValue(values[key])
+		if err != nil {
+			return errors.Wrapf(err, "invalid value of %q in config file %s", key, path)
+		}
+		if err := setValue(flag, value); err != nil {
+			return errors.Wrapf(err, "invalid value of %q in config file %s", key, path)
+		}
+	}
+	if len(unknown) > 0 {
+		return errors.Errorf("unknown keys in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// fileValue converts the value read from the file to the flag value. Lists are passed as comma separated values.
+func fileValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		return "", errors.New("value must be a scalar or a list")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// Print writes the effective values of the flags as YAML accepted by WithFile. Values of the secret flags are
+// redacted and the omitted flags are not printed.
+func Print(w io.Writer, f *pflag.FlagSet, secrets, omitted []string) error {
+	values := map[string]any{}
+	f.VisitAll(func(flag *pflag.Flag) {
+		if lo.Contains(omitted, flag.Name) {
+			return
+		}
+		switch {
+		case lo.Contains(secrets, flag.Name) && flag.Value.String() != "" && flag.Value.String() != "[]":
+			values[flag.Name] = redacted
+		default:
+			values[flag.Name] = printValue(flag)
+		}
+	})
+
+	content, err := yaml.Marshal(values)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = w.Write(content)
+	return errors.WithStack(err)
+}
+
+// printValue returns the value of the flag typed the way it is written in the YAML file.
+func printValue(flag *pflag.Flag) any {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		return slice.GetSlice()
+	}
+	value := flag.Value.String()
+	switch flag.Value.Type() {
+	case "bool":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "int", "int8", "int16", "int32", "int64":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		if u, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return u
+		}
+	}
+	return value
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type fileTestConfig struct {
+	port    int
+	nodes   []string
+	timeout time.Duration
+	verbose bool
+	keys    []string
+}
+
+func newFileTestFlagSet() (*pflag.FlagSet, *fileTestConfig) {
+	conf := &fileTestConfig{}
+	flagSet := pflag.NewFlagSet("temp", pflag.ContinueOnError)
+	flagSet.IntVar(&conf.port, "port", 1, "defines port")
+	flagSet.StringSliceVar(&conf.nodes, "nodes", []string{"default"}, "defines nodes")
+	flagSet.DurationVar(&conf.timeout, "timeout", time.Second, "defines timeout")
+	flagSet.BoolVar(&conf.verbose, "verbose", false, "defines verbosity")
+	flagSet.StringSliceVar(&conf.keys, "api-keys", nil, "defines keys")
+	return flagSet, conf
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestWithFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "config.yaml",
+			content: `
+port: 12
+nodes: [first, second]
+timeout: 5s
+verbose: true
+`,
+		},
+		{
+			name: "config.toml",
+			content: `
+port = 12
+nodes = ["first", "second"]
+timeout = "5s"
+verbose = true
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireT := require.New(t)
+			flagSet, conf := newFileTestFlagSet()
+			requireT.NoError(flagSet.Parse(nil))
+			requireT.NoError(WithFile(flagSet, writeConfigFile(t, tt.name, tt.content)))
+
+			requireT.Equal(12, conf.port)
+			requireT.Equal([]string{"first", "second"}, conf.nodes)
+			requireT.Equal(5*time.Second, conf.timeout)
+			requireT.True(conf.verbose)
+		})
+	}
+}
+
+func TestWithFile_Precedence(t *testing.T) {
+	requireT := require.New(t)
+	flagSet, conf := newFileTestFlagSet()
+	path := writeConfigFile(t, "config.yaml", "port: 12\ntimeout: 5s\nverbose: true\nnodes: [first, second]\n")
+	t.Setenv("TIMEOUT", "7s")
+	t.Setenv("PORT", "13")
+	t.Setenv("NODES", "third")
+
+	requireT.NoError(flagSet.Parse([]string{"--port", "14"}))
+	requireT.NoError(WithFile(flagSet, path))
+	requireT.NoError(WithEnv(flagSet, ""))
+
+	requireT.Equal(14, conf.port)
+	requireT.Equal(7*time.Second, conf.timeout)
+	requireT.True(conf.verbose)
+	// list set in the env var replaces the one set in the file
+	requireT.Equal([]string{"third"}, conf.nodes)
+}
+
+func TestWithFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "unknown_key",
+			file:    "config.yaml",
+			content: "port: 12\nprot: 13\n",
+		},
+		{
+			name:    "invalid_value",
+			file:    "config.yaml",
+			content: "port: abc\n",
+		},
+		{
+			name:    "nested_value",
+			file:    "config.yaml",
+			content: "nodes:\n  first: a\n",
+		},
+		{
+			name:    "unsupported_format",
+			file:    "config.json",
+			content: `{"port": 12}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet, _ := newFileTestFlagSet()
+			require.NoError(t, flagSet.Parse(nil))
+			require.Error(t, WithFile(flagSet, writeConfigFile(t, tt.file, tt.content)))
+		})
+	}
+}
+
+func TestPrint(t *testing.T) {
+	requireT := require.New(t)
+	flagSet, _ := newFileTestFlagSet()
+	requireT.NoError(flagSet.Parse([]string{"--port", "14", "--api-keys", "secret"}))
+
+	buf := &bytes.Buffer{}
+	requireT.NoError(Print(buf, flagSet, []string{"api-keys"}, []string{"verbose"}))
+	requireT.NotContains(buf.String(), "secret")
+
+	printed := map[string]any{}
+	requireT.NoError(yaml.Unmarshal(buf.Bytes(), &printed))
+	requireT.Equal(map[string]any{
+		"port":     14,
+		"nodes":    []any{"default"},
+		"timeout":  "1s",
+		"api-keys": "<redacted>",
+	}, printed)
+
+	// printed config is accepted as the config file
+	flagSet, conf := newFileTestFlagSet()
+	requireT.NoError(flagSet.Parse(nil))
+	delete(printed, "api-keys")
+	content, err := yaml.Marshal(printed)
+	requireT.NoError(err)
+	requireT.NoError(WithFile(flagSet, writeConfigFile(t, "config.yml", string(content))))
+	requireT.Equal(14, conf.port)
+}
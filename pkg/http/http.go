@@ -76,7 +76,8 @@ func (s Server) Start(ctx context.Context, listenAddress string, forceShutdownTi
 
 func (s Server) listen(ctx context.Context, listener net.Listener) error {
 	logger.Get(ctx).Info("Started listening for http connections", zap.Stringer("address", listener.Addr()))
-	if err := http.Serve(listener, s.Echo); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	// server owned by echo is used, so it is stopped by Shutdown
+	if err := s.Echo.Server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return errors.Wrap(err, "error listening for connections")
 	}
 	return errors.WithStack(ctx.Err())
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"strings"
@@ -40,6 +41,10 @@ func prepareRequestContextMiddleware(log *zap.Logger) func(HandlerFunc) HandlerF
 				zap.String("method", r.Method),
 			)
 			ctx := logger.WithLogger(c.Request().Context(), logNew)
+			ctx = context.WithValue(ctx, requestInfoKey{}, RequestInfo{
+				RequestID: rid,
+				ClientIP:  userIP,
+			})
 			request := c.Request().WithContext(ctx)
 			c.SetRequest(request)
 			return next(c)
@@ -47,6 +52,21 @@ func prepareRequestContextMiddleware(log *zap.Logger) func(HandlerFunc) HandlerF
 	}
 }
 
+type requestInfoKey struct{}
+
+// RequestInfo contains the details of the http request being processed.
+type RequestInfo struct {
+	RequestID string
+	ClientIP  net.IP
+}
+
+// RequestInfoFromContext returns the details of the http request stored in the context by the server.
+// Zero value is returned if the context does not come from the http request.
+func RequestInfoFromContext(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
+
 // IPFromRequest returns IP of the client sending http request.
 func IPFromRequest(r *http.Request) (i net.IP, err error) {
 	host, _, err := net.SplitHostPort(r.RemoteAddr)
@@ -0,0 +1,140 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrKeyReused is returned if the key has been already used for a different request.
+var ErrKeyReused = errors.New("idempotency key has been used for a different request")
+
+// Response is the stored response of the request.
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+type entry struct {
+	fingerprint string
+	done        chan struct{}
+	completed   bool
+	response    Response
+	err         error
+	expiresAt   time.Time
+}
+
+// Cache stores responses of the requests identified by idempotency keys for the configured window.
+type Cache struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewCache returns new idempotency cache storing responses for the duration of window.
+func NewCache(window time.Duration) *Cache {
+	return &Cache{
+		window:  window,
+		entries: map[string]*entry{},
+	}
+}
+
+// Do executes fn only if there is no stored result for the key. If the result is stored, it is returned
+// together with true. If the request with the same key is in progress, Do waits until it completes.
+// Errors of fn are stored too, unless retryable tells the request had no effect, so the next request with the same
+// key executes it again. Fingerprint identifies the content of the request, ErrKeyReused is returned if the key
+// has been used with a different one.
+func (c *Cache) Do(
+	ctx context.Context,
+	key, fingerprint string,
+	fn func() (Response, error),
+	retryable func(err error) bool,
+) (Response, bool, error) {
+	for {
+		c.mu.Lock()
+		e, exists := c.entries[key]
+		if exists && e.completed && time.Now().After(e.expiresAt) {
+			delete(c.entries, key)
+			exists = false
+		}
+		if !exists {
+			e = &entry{
+				fingerprint: fingerprint,
+				done:        make(chan struct{}),
+			}
+			c.entries[key] = e
+			c.mu.Unlock()
+			return c.execute(key, e, fn, retryable)
+		}
+		c.mu.Unlock()
+		if e.fingerprint != fingerprint {
+			return Response{}, false, errors.WithStack(ErrKeyReused)
+		}
+
+		select {
+		case <-ctx.Done():
+			return Response{}, false, errors.WithStack(ctx.Err())
+		case <-e.done:
+		}
+
+		c.mu.Lock()
+		completed, response, err := e.completed, e.response, e.err
+		c.mu.Unlock()
+		if completed {
+			return response, true, err
+		}
+		// the request in progress failed, try to execute it again
+	}
+}
+
+func (c *Cache) execute(
+	key string,
+	e *entry,
+	fn func() (Response, error),
+	retryable func(err error) bool,
+) (Response, bool, error) {
+	var (
+		response Response
+		err      error
+	)
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if err == nil || !retryable(err) {
+			e.completed = true
+			e.response = response
+			e.err = err
+			e.expiresAt = time.Now().Add(c.window)
+		} else {
+			delete(c.entries, key)
+		}
+		close(e.done)
+	}()
+
+	response, err = fn()
+	return response, false, err
+}
+
+// Run runs cleaning task of the cache.
+func (c *Cache) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-time.After(c.window):
+			now := time.Now()
+			c.mu.Lock()
+			for key, e := range c.entries {
+				if e.completed && now.After(e.expiresAt) {
+					delete(c.entries, key)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package idempotency
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CoreumFoundation/faucet/client/coreum"
+)
+
+var errRetryable = errors.New("retryable")
+
+func isRetryable(err error) bool {
+	return errors.Is(err, errRetryable)
+}
+
+func TestCacheReplaysResponse(t *testing.T) {
+	requireT := require.New(t)
+
+	cache := NewCache(time.Hour)
+	var calls atomic.Int32
+	fn := func() (Response, error) {
+		calls.Add(1)
+		return Response{Status: 200, Body: []byte("body")}, nil
+	}
+
+	resp, replayed, err := cache.Do(t.Context(), "key", "fingerprint", fn, isRetryable)
+	requireT.NoError(err)
+	requireT.False(replayed)
+	requireT.Equal("body", string(resp.Body))
+
+	resp, replayed, err = cache.Do(t.Context(), "key", "fingerprint", fn, isRetryable)
+	requireT.NoError(err)
+	requireT.True(replayed)
+	requireT.Equal("body", string(resp.Body))
+
+	_, replayed, err = cache.Do(t.Context(), "other-key", "fingerprint", fn, isRetryable)
+	requireT.NoError(err)
+	requireT.False(replayed)
+
+	requireT.EqualValues(2, calls.Load())
+}
+
+func TestCacheWaitsForRequestInProgress(t *testing.T) {
+	requireT := require.New(t)
+
+	cache := NewCache(time.Hour)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() (Response, error) {
+		calls.Add(1)
+		<-release
+		return Response{Status: 200}, nil
+	}
+
+	var wg sync.WaitGroup
+	var replayedCount atomic.Int32
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, replayed, err := cache.Do(t.Context(), "key", "fingerprint", fn, isRetryable)
+			assert.NoError(t, err)
+			if replayed {
+				replayedCount.Add(1)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	requireT.EqualValues(1, calls.Load())
+	requireT.EqualValues(4, replayedCount.Load())
+}
+
+func TestCacheRejectsReusedKey(t *testing.T) {
+	requireT := require.New(t)
+
+	cache := NewCache(time.Hour)
+	fn := func() (Response, error) {
+		return Response{Status: 200}, nil
+	}
+	_, _, err := cache.Do(t.Context(), "key", "fingerprint", fn, isRetryable)
+	requireT.NoError(err)
+
+	_, _, err = cache.Do(t.Context(), "key", "other-fingerprint", fn, isRetryable)
+	requireT.ErrorIs(err, ErrKeyReused)
+}
+
+func TestCacheDoesNotStoreRetryableFailures(t *testing.T) {
+	requireT := require.New(t)
+
+	cache := NewCache(time.Hour)
+	_, _, err := cache.Do(t.Context(), "key", "fingerprint", func() (Response, error) {
+		return Response{}, errRetryable
+	}, isRetryable)
+	requireT.ErrorIs(err, errRetryable)
+
+	_, replayed, err := cache.Do(t.Context(), "key", "fingerprint", func() (Response, error) {
+		return Response{Status: 200}, nil
+	}, isRetryable)
+	requireT.NoError(err)
+	requireT.False(replayed)
+}
+
+func TestCacheStoresTerminalFailures(t *testing.T) {
+	requireT := require.New(t)
+
+	cache := NewCache(time.Hour)
+	var calls atomic.Int32
+	fn := func() (Response, error) {
+		calls.Add(1)
+		return Response{}, errors.Wrap(coreum.ErrOutcomeUnknown, "tx not found")
+	}
+	_, replayed, err := cache.Do(t.Context(), "key", "fingerprint", fn, isRetryable)
+	requireT.ErrorIs(err, coreum.ErrOutcomeUnknown)
+	requireT.False(replayed)
+
+	// transfer might have been sent, so it is never sent again with the same key
+	_, replayed, err = cache.Do(t.Context(), "key", "fingerprint", fn, isRetryable)
+	requireT.ErrorIs(err, coreum.ErrOutcomeUnknown)
+	requireT.True(replayed)
+	requireT.EqualValues(1, calls.Load())
+}
+
+func TestCacheExpiresResponses(t *testing.T) {
+	requireT := require.New(t)
+
+	cache := NewCache(time.Millisecond)
+	fn := func() (Response, error) {
+		return Response{Status: 200}, nil
+	}
+	_, _, err := cache.Do(t.Context(), "key", "fingerprint", fn, isRetryable)
+	requireT.NoError(err)
+
+	time.Sleep(5 * time.Millisecond)
+	_, replayed, err := cache.Do(t.Context(), "key", "fingerprint", fn, isRetryable)
+	requireT.NoError(err)
+	requireT.False(replayed)
+}
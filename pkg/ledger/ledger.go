@@ -0,0 +1,383 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Errors returned by the ledger.
+var (
+	ErrNotFound      = errors.New("record not found")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+var (
+	recordsBucket = []byte("records")
+	idsBucket     = []byte("ids")
+	refillsBucket = []byte("refills")
+	// addressesBucket indexes the records by the destination address, its keys are the address, zero byte
+	// and the key of the record.
+	addressesBucket = []byte("addresses")
+	// txHashesBucket indexes the records by the hash of the tx, its keys are built the same way.
+	txHashesBucket = []byte("txHashes")
+)
+
+// Status is the status of the fund request.
+type Status string
+
+// Statuses of the fund request.
+const (
+	StatusQueued    Status = "queued"
+	StatusBatched   Status = "batched"
+	StatusBroadcast Status = "broadcast"
+	StatusCommitted Status = "committed"
+	StatusFailed    Status = "failed"
+	// StatusUnknown is set if the faucet stopped before the result of the broadcast tx was known.
+	StatusUnknown Status = "unknown"
+)
+
+var statuses = map[Status]bool{
+	StatusQueued:    true,
+	StatusBatched:   true,
+	StatusBroadcast: true,
+	StatusCommitted: true,
+	StatusFailed:    true,
+	StatusUnknown:   true,
+}
+
+// Valid tells if the status is one of the known statuses.
+func (s Status) Valid() bool {
+	return statuses[s]
+}
+
+// Record is the entry stored in the ledger for every fund request.
+type Record struct {
+	ID             string `json:"id"`
+	RequestID      string `json:"requestId"`
+	ClientIP       string `json:"clientIp"`
+	Address        string `json:"address"`
+	Coins          string `json:"coins"`
+	FundingAddress string `json:"fundingAddress,omitempty"`
+	TxHash         string `json:"txHash,omitempty"`
+	Status         Status `json:"status"`
+	Error          string `json:"error,omitempty"`
+	// ReconcileAttempts is the number of times the unknown outcome of the request was checked on startup.
+	ReconcileAttempts int       `json:"reconcileAttempts,omitempty"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// Filter defines the criteria which records returned by Query must match. Zero fields are ignored.
+type Filter struct {
+	Address string
+	TxHash  string
+	Status  Status
+	From    time.Time
+	To      time.Time
+}
+
+func (f Filter) matches(record Record) bool {
+	switch {
+	case f.Address != "" && record.Address != f.Address,
+		f.TxHash != "" && record.TxHash != f.TxHash,
+		f.Status != "" && record.Status != f.Status,
+		!f.To.IsZero() && record.CreatedAt.After(f.To):
+		return false
+	default:
+		return true
+	}
+}
+
+// Store is the ledger persisted in the embedded database.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the ledger stored in the file at path, the file is created if it doesn't exist.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open ledger at %s", path)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		addressesIndexed := tx.Bucket(addressesBucket) != nil
+		txHashesIndexed := tx.Bucket(txHashesBucket) != nil
+		for _, bucket := range [][]byte{recordsBucket, idsBucket, refillsBucket, addressesBucket, txHashesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if addressesIndexed && txHashesIndexed {
+			return nil
+		}
+		// ledger created before the indexes were introduced
+		return tx.Bucket(recordsBucket).ForEach(func(k, v []byte) error {
+			var record Record
+			if err := json.Unmarshal(v, &record); err != nil {
+				return errors.WithStack(err)
+			}
+			if !addressesIndexed {
+				if err := tx.Bucket(addressesBucket).Put(indexKey(record.Address, k), nil); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			if !txHashesIndexed {
+				return indexTxHash(tx, "", record.TxHash, k)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return errors.WithStack(s.db.Close())
+}
+
+// Create stores new record in the ledger. ID and timestamps of the record are set by the ledger.
+func (s *Store) Create(record Record) (Record, error) {
+	record.ID = uuid.New().String()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		// timestamp is taken inside the transaction, so the records are ordered by the creation time
+		record.CreatedAt = time.Now().UTC()
+		record.UpdatedAt = record.CreatedAt
+
+		records := tx.Bucket(recordsBucket)
+		seq, err := records.NextSequence()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		key := seqToKey(seq)
+		if err := tx.Bucket(idsBucket).Put([]byte(record.ID), key); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := tx.Bucket(addressesBucket).Put(indexKey(record.Address, key), nil); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := indexTxHash(tx, "", record.TxHash, key); err != nil {
+			return err
+		}
+		return put(records, key, record)
+	})
+	if err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
+
+// Update applies the update function to the stored record.
+func (s *Store) Update(id string, update func(record *Record)) (Record, error) {
+	var record Record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		record, err = updateRecord(tx, id, update)
+		return err
+	})
+	if err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
+
+// UpdateMany applies the update function to the stored records in a single transaction. Nothing is updated if any
+// of the records doesn't exist.
+func (s *Store) UpdateMany(ids []string, update func(record *Record)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			if _, err := updateRecord(tx, id, update); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func updateRecord(tx *bolt.Tx, id string, update func(record *Record)) (Record, error) {
+	key := tx.Bucket(idsBucket).Get([]byte(id))
+	if key == nil {
+		return Record{}, errors.Wrapf(ErrNotFound, "id: %s", id)
+	}
+	records := tx.Bucket(recordsBucket)
+	var record Record
+	if err := get(records, key, &record); err != nil {
+		return Record{}, err
+	}
+
+	txHash := record.TxHash
+	update(&record)
+	record.UpdatedAt = time.Now().UTC()
+	if err := indexTxHash(tx, txHash, record.TxHash, key); err != nil {
+		return Record{}, err
+	}
+	return record, put(records, key, record)
+}
+
+// indexTxHash moves the record with the key from the old tx hash to the new one in the index.
+func indexTxHash(tx *bolt.Tx, oldTxHash, newTxHash string, key []byte) error {
+	if oldTxHash == newTxHash {
+		return nil
+	}
+	txHashes := tx.Bucket(txHashesBucket)
+	if oldTxHash != "" {
+		if err := txHashes.Delete(indexKey(oldTxHash, key)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if newTxHash != "" {
+		if err := txHashes.Put(indexKey(newTxHash, key), nil); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// Get returns the record by its ID.
+func (s *Store) Get(id string) (Record, error) {
+	var record Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(idsBucket).Get([]byte(id))
+		if key == nil {
+			return errors.Wrapf(ErrNotFound, "id: %s", id)
+		}
+		return get(tx.Bucket(recordsBucket), key, &record)
+	})
+	if err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
+
+// Query returns records matching the filter, starting from the newest one. Cursor returned by the previous call
+// is used to fetch the next page, empty cursor means the first page. Returned cursor is empty if there are
+// no more records.
+func (s *Store) Query(filter Filter, cursor string, limit int) ([]Record, string, error) {
+	var startKey []byte
+	if cursor != "" {
+		var err error
+		startKey, err = hex.DecodeString(cursor)
+		if err != nil || len(startKey) != 8 {
+			return nil, "", errors.Wrapf(ErrInvalidCursor, "cursor: %s", cursor)
+		}
+	}
+
+	result := []Record{}
+	var nextCursor string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		records := tx.Bucket(recordsBucket)
+		// records of the tx or the address are found by the index instead of scanning all of them
+		it := &keyIterator{cursor: records.Cursor()}
+		switch {
+		case filter.TxHash != "":
+			it = &keyIterator{
+				cursor: tx.Bucket(txHashesBucket).Cursor(),
+				prefix: indexKey(filter.TxHash, nil),
+			}
+		case filter.Address != "":
+			it = &keyIterator{
+				cursor: tx.Bucket(addressesBucket).Cursor(),
+				prefix: indexKey(filter.Address, nil),
+			}
+		}
+
+		for k := it.first(startKey); k != nil; k = it.prev() {
+			var record Record
+			if err := get(records, k, &record); err != nil {
+				return err
+			}
+			// records are stored in the order of creation, so all the remaining ones are older
+			if !filter.From.IsZero() && record.CreatedAt.Before(filter.From) {
+				return nil
+			}
+			if !filter.matches(record) {
+				continue
+			}
+
+			result = append(result, record)
+			if len(result) == limit {
+				nextCursor = hex.EncodeToString(k)
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return result, nextCursor, nil
+}
+
+// keyIterator iterates the keys of the records from the newest one. Keys of the bucket start with the prefix
+// followed by the key of the record.
+type keyIterator struct {
+	cursor *bolt.Cursor
+	prefix []byte
+}
+
+// first returns the key of the newest record older than the one with startKey, or of the newest one if startKey
+// is nil.
+func (it *keyIterator) first(startKey []byte) []byte {
+	if startKey == nil {
+		startKey = bytes.Repeat([]byte{0xff}, 8)
+	}
+	// cursor points to the last record returned in the previous page or after the newest one
+	k, _ := it.cursor.Seek(append(append([]byte{}, it.prefix...), startKey...))
+	if k == nil {
+		k, _ = it.cursor.Last()
+	} else {
+		k, _ = it.cursor.Prev()
+	}
+	return it.key(k)
+}
+
+func (it *keyIterator) prev() []byte {
+	k, _ := it.cursor.Prev()
+	return it.key(k)
+}
+
+func (it *keyIterator) key(k []byte) []byte {
+	if !bytes.HasPrefix(k, it.prefix) {
+		return nil
+	}
+	return k[len(it.prefix):]
+}
+
+func indexKey(value string, key []byte) []byte {
+	return append(append([]byte(value), 0), key...)
+}
+
+func get(bucket *bolt.Bucket, key []byte, record *Record) error {
+	value := bucket.Get(key)
+	if value == nil {
+		return errors.Wrapf(ErrNotFound, "key: %x", key)
+	}
+	return errors.WithStack(json.Unmarshal(value, record))
+}
+
+func put(bucket *bolt.Bucket, key []byte, record Record) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(bucket.Put(key, value))
+}
+
+func seqToKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
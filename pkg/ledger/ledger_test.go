@@ -0,0 +1,242 @@
+package ledger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestStore(t *testing.T) {
+	requireT := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "ledger.db")
+	store, err := Open(path)
+	requireT.NoError(err)
+
+	record, err := store.Create(Record{
+		RequestID: "request-id",
+		ClientIP:  "1.2.3.4",
+		Address:   "devcore1address",
+		Coins:     "10udevcore",
+		Status:    StatusQueued,
+	})
+	requireT.NoError(err)
+	requireT.NotEmpty(record.ID)
+	requireT.False(record.CreatedAt.IsZero())
+
+	_, err = store.Update(record.ID, func(record *Record) {
+		record.Status = StatusCommitted
+		record.TxHash = "tx-hash"
+		record.FundingAddress = "devcore1funding"
+	})
+	requireT.NoError(err)
+
+	_, err = store.Update("unknown", func(record *Record) {})
+	requireT.ErrorIs(err, ErrNotFound)
+
+	// nothing is updated if any of the records doesn't exist
+	err = store.UpdateMany([]string{record.ID, "unknown"}, func(record *Record) {
+		record.Status = StatusFailed
+	})
+	requireT.ErrorIs(err, ErrNotFound)
+
+	// records must survive reopening the database
+	requireT.NoError(store.Close())
+	store, err = Open(path)
+	requireT.NoError(err)
+	t.Cleanup(func() {
+		requireT.NoError(store.Close())
+	})
+
+	stored, err := store.Get(record.ID)
+	requireT.NoError(err)
+	requireT.Equal(StatusCommitted, stored.Status)
+	requireT.Equal("tx-hash", stored.TxHash)
+	requireT.Equal("devcore1funding", stored.FundingAddress)
+	requireT.Equal("request-id", stored.RequestID)
+	requireT.Equal("1.2.3.4", stored.ClientIP)
+	requireT.Equal(record.CreatedAt, stored.CreatedAt)
+
+	_, err = store.Get("unknown")
+	requireT.ErrorIs(err, ErrNotFound)
+}
+
+func TestStoreQuery(t *testing.T) {
+	requireT := require.New(t)
+
+	store, err := Open(filepath.Join(t.TempDir(), "ledger.db"))
+	requireT.NoError(err)
+	t.Cleanup(func() {
+		requireT.NoError(store.Close())
+	})
+
+	var ids []string
+	for i := range 10 {
+		address := "devcore1first"
+		if i%2 == 1 {
+			address = "devcore1second"
+		}
+		record, err := store.Create(Record{
+			Address: address,
+			Status:  StatusQueued,
+		})
+		requireT.NoError(err)
+		ids = append(ids, record.ID)
+	}
+	_, err = store.Update(ids[3], func(record *Record) {
+		record.Status = StatusCommitted
+		record.TxHash = "tx-hash"
+	})
+	requireT.NoError(err)
+
+	// pagination returns all the records starting from the newest one
+	var queried []string
+	cursor := ""
+	for {
+		records, nextCursor, err := store.Query(Filter{}, cursor, 3)
+		requireT.NoError(err)
+		for _, record := range records {
+			queried = append(queried, record.ID)
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	requireT.Len(queried, len(ids))
+	for i, id := range queried {
+		requireT.Equal(ids[len(ids)-1-i], id)
+	}
+
+	// records of the address are paginated the same way using the index
+	queried = nil
+	cursor = ""
+	for {
+		records, nextCursor, err := store.Query(Filter{Address: "devcore1second"}, cursor, 2)
+		requireT.NoError(err)
+		for _, record := range records {
+			requireT.Equal("devcore1second", record.Address)
+			queried = append(queried, record.ID)
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	requireT.Equal([]string{ids[9], ids[7], ids[5], ids[3], ids[1]}, queried)
+
+	records, _, err := store.Query(Filter{Address: "devcore1third"}, "", 10)
+	requireT.NoError(err)
+	requireT.Empty(records)
+
+	records, _, err = store.Query(Filter{TxHash: "tx-hash", Status: StatusCommitted}, "", 10)
+	requireT.NoError(err)
+	requireT.Len(records, 1)
+	requireT.Equal(ids[3], records[0].ID)
+
+	// changed tx hash is moved in the index
+	_, err = store.Update(ids[3], func(record *Record) {
+		record.TxHash = "other-tx-hash"
+	})
+	requireT.NoError(err)
+	records, _, err = store.Query(Filter{TxHash: "tx-hash"}, "", 10)
+	requireT.NoError(err)
+	requireT.Empty(records)
+	records, _, err = store.Query(Filter{TxHash: "other-tx-hash", Address: "devcore1second"}, "", 10)
+	requireT.NoError(err)
+	requireT.Len(records, 1)
+	requireT.Equal(ids[3], records[0].ID)
+	records, _, err = store.Query(Filter{TxHash: "other-tx-hash", Address: "devcore1first"}, "", 10)
+	requireT.NoError(err)
+	requireT.Empty(records)
+
+	records, _, err = store.Query(Filter{From: time.Now().Add(time.Hour)}, "", 10)
+	requireT.NoError(err)
+	requireT.Empty(records)
+
+	records, _, err = store.Query(Filter{To: time.Now().Add(-time.Hour)}, "", 10)
+	requireT.NoError(err)
+	requireT.Empty(records)
+
+	_, _, err = store.Query(Filter{}, "invalid", 10)
+	requireT.ErrorIs(err, ErrInvalidCursor)
+}
+
+func TestStoreIndexesBuiltOnOpen(t *testing.T) {
+	requireT := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "ledger.db")
+	store, err := Open(path)
+	requireT.NoError(err)
+	record, err := store.Create(Record{Address: "devcore1first", TxHash: "tx-hash", Status: StatusQueued})
+	requireT.NoError(err)
+
+	// ledger created before the indexes were introduced
+	requireT.NoError(store.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(addressesBucket); err != nil {
+			return err
+		}
+		return tx.DeleteBucket(txHashesBucket)
+	}))
+	requireT.NoError(store.Close())
+
+	store, err = Open(path)
+	requireT.NoError(err)
+	t.Cleanup(func() {
+		requireT.NoError(store.Close())
+	})
+
+	records, _, err := store.Query(Filter{Address: "devcore1first"}, "", 10)
+	requireT.NoError(err)
+	requireT.Len(records, 1)
+	requireT.Equal(record.ID, records[0].ID)
+
+	records, _, err = store.Query(Filter{TxHash: "tx-hash"}, "", 10)
+	requireT.NoError(err)
+	requireT.Len(records, 1)
+	requireT.Equal(record.ID, records[0].ID)
+}
+
+func TestStoreRefills(t *testing.T) {
+	requireT := require.New(t)
+
+	store, err := Open(filepath.Join(t.TempDir(), "ledger.db"))
+	requireT.NoError(err)
+	t.Cleanup(func() {
+		requireT.NoError(store.Close())
+	})
+
+	first, err := store.CreateRefill(Refill{TxHash: "first", Amount: "10", Denom: "udevcore"})
+	requireT.NoError(err)
+	requireT.NotEmpty(first.ID)
+	since := time.Now().UTC()
+	_, err = store.CreateRefill(Refill{TxHash: "second", Amount: "20", Denom: "udevcore"})
+	requireT.NoError(err)
+	_, err = store.CreateRefill(Refill{TxHash: "third", Amount: "30", Denom: "udevcore"})
+	requireT.NoError(err)
+
+	refills, err := store.Refills(since)
+	requireT.NoError(err)
+	requireT.Len(refills, 2)
+	requireT.Equal("second", refills[0].TxHash)
+	requireT.Equal("third", refills[1].TxHash)
+
+	refills, err = store.Refills(time.Time{})
+	requireT.NoError(err)
+	requireT.Len(refills, 3)
+
+	updated, err := store.UpdateRefill(first.ID, func(refill *Refill) {
+		refill.Status = StatusFailed
+	})
+	requireT.NoError(err)
+	requireT.Equal("first", updated.TxHash)
+	refills, err = store.Refills(time.Time{})
+	requireT.NoError(err)
+	requireT.Equal(StatusFailed, refills[0].Status)
+
+	_, err = store.UpdateRefill("missing", func(*Refill) {})
+	requireT.ErrorIs(err, ErrNotFound)
+}
@@ -0,0 +1,100 @@
+package ledger
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Refill is the entry stored in the ledger for every transfer refilling the funding accounts from the treasury.
+// It is created before the tx is broadcast, so the refill counts toward the budget until it is known to be failed.
+type Refill struct {
+	ID         string    `json:"id"`
+	Status     Status    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	TxHash     string    `json:"txHash"`
+	Treasury   string    `json:"treasury"`
+	Recipients []string  `json:"recipients"`
+	Amount     string    `json:"amount"`
+	Denom      string    `json:"denom"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// CreateRefill stores new refill in the ledger. ID and timestamp of the refill are set by the ledger.
+func (s *Store) CreateRefill(refill Refill) (Refill, error) {
+	refill.ID = uuid.New().String()
+	refill.CreatedAt = time.Now().UTC()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		refills := tx.Bucket(refillsBucket)
+		seq, err := refills.NextSequence()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		value, err := json.Marshal(refill)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(refills.Put(seqToKey(seq), value))
+	})
+	if err != nil {
+		return Refill{}, err
+	}
+	return refill, nil
+}
+
+// UpdateRefill applies the update function to the stored refill.
+func (s *Store) UpdateRefill(id string, update func(refill *Refill)) (Refill, error) {
+	var refill Refill
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		refills := tx.Bucket(refillsBucket)
+		c := refills.Cursor()
+		// refill is updated shortly after it is created, so the search starts from the newest one
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if err := json.Unmarshal(v, &refill); err != nil {
+				return errors.WithStack(err)
+			}
+			if refill.ID != id {
+				continue
+			}
+			update(&refill)
+			value, err := json.Marshal(refill)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return errors.WithStack(refills.Put(k, value))
+		}
+		return errors.Wrapf(ErrNotFound, "refill id: %s", id)
+	})
+	if err != nil {
+		return Refill{}, err
+	}
+	return refill, nil
+}
+
+// Refills returns the refills created since the time, starting from the oldest one.
+func (s *Store) Refills(since time.Time) ([]Refill, error) {
+	refills := []Refill{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(refillsBucket).Cursor()
+		// refills are stored in the order of creation, so the search starts from the newest one
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var refill Refill
+			if err := json.Unmarshal(v, &refill); err != nil {
+				return errors.WithStack(err)
+			}
+			if refill.CreatedAt.Before(since) {
+				break
+			}
+			refills = append([]Refill{refill}, refills...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refills, nil
+}
@@ -23,7 +23,7 @@ type period struct {
 	counters map[string]uint64
 }
 
-func (p period) GetProportionally(ip net.IP) uint64 {
+func (p period) GetProportionally(key string) uint64 {
 	if p.duration.Nanoseconds() == 0 {
 		return 0
 	}
@@ -31,15 +31,21 @@ func (p period) GetProportionally(ip net.IP) uint64 {
 	if overlappedDuration >= p.duration {
 		return 0
 	}
-	return uint64(float64(p.counters[string(ip)]) * float64(p.duration-overlappedDuration) / float64(p.duration))
+	return uint64(float64(p.counters[key]) * float64(p.duration-overlappedDuration) / float64(p.duration))
 }
 
-func (p period) Get(ip net.IP) uint64 {
-	return p.counters[string(ip)]
+func (p period) Get(key string) uint64 {
+	return p.counters[key]
 }
 
-func (p period) Increment(ip net.IP) {
-	p.counters[string(ip)]++
+func (p period) Increment(key string) {
+	p.counters[key]++
+}
+
+func (p period) Decrement(key string) {
+	if p.counters[key] > 0 {
+		p.counters[key]--
+	}
 }
 
 // WeightedWindowLimiter imlements rate limiting using weighted window algorithm.
@@ -63,24 +69,90 @@ func NewWeightedWindowLimiter(limit uint64, duration time.Duration) *WeightedWin
 
 // IsRequestAllowed tells if request should be handled or rejected due to exhausted rate limit.
 func (l *WeightedWindowLimiter) IsRequestAllowed(ip net.IP) bool {
+	return l.IsKeyAllowed(string(ip))
+}
+
+// Increment will consume rate limit by 1.
+func (l *WeightedWindowLimiter) Increment(ip net.IP) {
+	l.IncrementKey(string(ip))
+}
+
+// IsKeyAllowed tells if request counted under the key should be handled or rejected due to exhausted rate limit.
+func (l *WeightedWindowLimiter) IsKeyAllowed(key string) bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	return l.previous.GetProportionally(ip)+l.current.Get(ip) <= l.limit
+	return l.previous.GetProportionally(key)+l.current.Get(key) <= l.limit
 }
 
-// Increment will consume rate limit by 1.
-func (l *WeightedWindowLimiter) Increment(ip net.IP) {
-	l.current.Increment(ip)
+// IncrementKey will consume rate limit of the key by 1.
+func (l *WeightedWindowLimiter) IncrementKey(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.current.Increment(key)
+}
+
+// Reserve consumes rate limit of the key by 1 if it isn't exhausted, the check and the increment are atomic, so
+// concurrent requests can't exceed the limit. Unlike IsKeyAllowed, at most limit requests are allowed in the window
+// and zero limit disables the limiter.
+func (l *WeightedWindowLimiter) Reserve(key string) bool {
+	return l.reserve(key, func(used, limit uint64) bool {
+		return limit == 0 || used < limit
+	})
+}
+
+// reserveInclusive consumes rate limit of the key by 1 the same way as Reserve, but the request is allowed as long
+// as the used rate limit doesn't exceed the limit, the same as in IsKeyAllowed.
+func (l *WeightedWindowLimiter) reserveInclusive(key string) bool {
+	return l.reserve(key, func(used, limit uint64) bool {
+		return used <= limit
+	})
+}
+
+// reserve increments the counter of the key if allowed tells the rate limit is not exhausted. Reservations are
+// counted even if the limiter is disabled, so they are refunded correctly if the limit is set in the meantime.
+func (l *WeightedWindowLimiter) reserve(key string, allowed func(used, limit uint64) bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !allowed(l.previous.GetProportionally(key)+l.current.Get(key), l.limit) {
+		return false
+	}
+	l.current.Increment(key)
+	return true
+}
+
+// Refund gives back the rate limit consumed by Reserve. Reservation made before the current period started
+// is not refunded.
+func (l *WeightedWindowLimiter) Refund(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.current.Decrement(key)
+}
+
+// SetLimit changes the limit while running. Requests already counted are kept, new period takes effect once
+// the current one ends.
+func (l *WeightedWindowLimiter) SetLimit(limit uint64, duration time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.duration = duration
 }
 
 // Run runs cleaning task of the limiter.
 func (l *WeightedWindowLimiter) Run(ctx context.Context) error {
 	for {
+		l.mu.Lock()
+		duration := l.current.duration
+		l.mu.Unlock()
+
 		select {
 		case <-ctx.Done():
 			return errors.WithStack(ctx.Err())
-		case <-time.After(l.duration):
+		case <-time.After(duration):
 			l.mu.Lock()
 			l.previous = l.current
 			l.current = newPeriod(l.duration)
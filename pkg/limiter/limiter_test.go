@@ -0,0 +1,102 @@
+package limiter
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeightedWindowLimiter(t *testing.T) {
+	requireT := require.New(t)
+	l := NewWeightedWindowLimiter(2, time.Hour)
+	ip := net.ParseIP("1.2.3.4")
+
+	// request is allowed until the counter exceeds the limit
+	for range 3 {
+		requireT.True(l.IsRequestAllowed(ip))
+		l.Increment(ip)
+	}
+	requireT.False(l.IsRequestAllowed(ip))
+	requireT.True(l.IsRequestAllowed(net.ParseIP("1.2.3.5")))
+
+	l.SetLimit(3, time.Hour)
+	requireT.True(l.IsRequestAllowed(ip))
+}
+
+func TestWeightedWindowLimiterReserve(t *testing.T) {
+	requireT := require.New(t)
+	l := NewWeightedWindowLimiter(2, 24*time.Hour)
+
+	requireT.True(l.Reserve("address"))
+	requireT.True(l.Reserve("address"))
+	requireT.False(l.Reserve("address"))
+	requireT.True(l.Reserve("other"))
+
+	l.Refund("address")
+	requireT.True(l.Reserve("address"))
+	requireT.False(l.Reserve("address"))
+
+	// zero limit disables the limiter
+	l.SetLimit(0, 24*time.Hour)
+	requireT.True(l.Reserve("address"))
+}
+
+func TestSubnetLimiter(t *testing.T) {
+	requireT := require.New(t)
+	coarseLimiter := NewWeightedWindowLimiter(3, time.Hour)
+	l := NewSubnetLimiter(
+		// the request is allowed as long as the used limit doesn't exceed it, so 2 requests are allowed
+		NewWeightedWindowLimiter(1, time.Hour),
+		Prefixes{IPv4: 24, IPv6: 64},
+		// exactly 3 requests are allowed
+		coarseLimiter,
+		Prefixes{IPv4: 16, IPv6: 48},
+	)
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{ip: "10.1.1.1", allowed: true},
+		{ip: "10.1.1.2", allowed: true},
+		// /24 network exhausted
+		{ip: "10.1.1.3", allowed: false},
+		{ip: "10.1.2.1", allowed: true},
+		// /16 network exhausted
+		{ip: "10.1.3.1", allowed: false},
+		{ip: "10.2.1.1", allowed: true},
+		{ip: "2001:db8:1:1::1", allowed: true},
+		{ip: "2001:db8:1:1:ffff::1", allowed: true},
+		// /64 network exhausted
+		{ip: "2001:db8:1:1:ffff::2", allowed: false},
+		{ip: "2001:db8:1:2::1", allowed: true},
+		// /48 network exhausted
+		{ip: "2001:db8:1:3::1", allowed: false},
+		{ip: "2001:db8:2:1::1", allowed: true},
+	}
+	for _, tt := range tests {
+		requireT.Equal(tt.allowed, l.Reserve(net.ParseIP(tt.ip)), tt.ip)
+	}
+
+	// refund gives back the limit of both networks
+	l.Refund(net.ParseIP("10.1.2.1"))
+	requireT.True(l.Reserve(net.ParseIP("10.1.3.1")))
+	requireT.False(l.Reserve(net.ParseIP("10.1.4.1")))
+
+	// rejected coarse reservation doesn't consume the limit of the /24 network, so it allows 2 more requests
+	coarseLimiter.SetLimit(0, time.Hour)
+	requireT.True(l.Reserve(net.ParseIP("10.1.4.1")))
+	requireT.True(l.Reserve(net.ParseIP("10.1.4.2")))
+	requireT.False(l.Reserve(net.ParseIP("10.1.4.3")))
+}
+
+func TestPrefixesKey(t *testing.T) {
+	requireT := require.New(t)
+	requireT.Equal("1.2.3.4/32", Prefixes{IPv4: 32, IPv6: 128}.key(net.ParseIP("1.2.3.4")))
+	requireT.Equal("1.2.3.0/24", Prefixes{IPv4: 24, IPv6: 64}.key(net.ParseIP("1.2.3.4")))
+	requireT.Equal("2001:db8::/32", Prefixes{IPv4: 24, IPv6: 32}.key(net.ParseIP("2001:db8:1:2::1")))
+	requireT.Error(Prefixes{IPv4: 33, IPv6: 64}.Validate())
+	requireT.Error(Prefixes{IPv4: 24, IPv6: 129}.Validate())
+}
@@ -0,0 +1,81 @@
+package limiter
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// Prefixes are the lengths of the prefixes the IPs of each address family are aggregated by.
+type Prefixes struct {
+	IPv4 int
+	IPv6 int
+}
+
+// Validate checks if the prefixes fit the address families.
+func (p Prefixes) Validate() error {
+	if p.IPv4 < 0 || p.IPv4 > 8*net.IPv4len {
+		return errors.Errorf("IPv4 prefix must be between 0 and %d, got %d", 8*net.IPv4len, p.IPv4)
+	}
+	if p.IPv6 < 0 || p.IPv6 > 8*net.IPv6len {
+		return errors.Errorf("IPv6 prefix must be between 0 and %d, got %d", 8*net.IPv6len, p.IPv6)
+	}
+	return nil
+}
+
+// key returns the network of the IP, used as the key of the counter.
+func (p Prefixes) key(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		mask := net.CIDRMask(p.IPv4, 8*net.IPv4len)
+		return (&net.IPNet{IP: ip4.Mask(mask), Mask: mask}).String()
+	}
+	mask := net.CIDRMask(p.IPv6, 8*net.IPv6len)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+}
+
+// SubnetLimiter counts the requests by the network the IP belongs to instead of the exact IP, so a user owning
+// the whole IPv6 /64 network doesn't get separate limit for each of its addresses. Requests are limited by
+// the network of each prefix and by the larger network of the coarse prefix, to throttle the whole cloud ranges.
+type SubnetLimiter struct {
+	limiter        *WeightedWindowLimiter
+	prefixes       Prefixes
+	coarseLimiter  *WeightedWindowLimiter
+	coarsePrefixes Prefixes
+}
+
+// NewSubnetLimiter returns new subnet limiter. Limits and cleanup of the limiters are managed by the caller.
+func NewSubnetLimiter(
+	limiter *WeightedWindowLimiter,
+	prefixes Prefixes,
+	coarseLimiter *WeightedWindowLimiter,
+	coarsePrefixes Prefixes,
+) *SubnetLimiter {
+	return &SubnetLimiter{
+		limiter:        limiter,
+		prefixes:       prefixes,
+		coarseLimiter:  coarseLimiter,
+		coarsePrefixes: coarsePrefixes,
+	}
+}
+
+// Reserve consumes rate limit of both networks the IP belongs to by 1, if neither of them is exhausted. The check
+// and the increment are atomic, so the burst of concurrent requests can't exceed the limits. The request is allowed
+// as long as the rate limit used by the network of the IP doesn't exceed its limit, while the coarse limit allows
+// exactly that many requests and zero disables it.
+func (l *SubnetLimiter) Reserve(ip net.IP) bool {
+	key := l.prefixes.key(ip)
+	if !l.limiter.reserveInclusive(key) {
+		return false
+	}
+	if !l.coarseLimiter.Reserve(l.coarsePrefixes.key(ip)) {
+		l.limiter.Refund(key)
+		return false
+	}
+	return true
+}
+
+// Refund gives back the rate limit of both networks the IP belongs to, consumed by Reserve.
+func (l *SubnetLimiter) Refund(ip net.IP) {
+	l.limiter.Refund(l.prefixes.key(ip))
+	l.coarseLimiter.Refund(l.coarsePrefixes.key(ip))
+}
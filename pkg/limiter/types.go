@@ -2,8 +2,9 @@ package limiter
 
 import "net"
 
-// PerIPLimiter defines an interface of IP rate limiter.
+// PerIPLimiter defines an interface of IP rate limiter. Reserve consumes the rate limit of the IP if it isn't
+// exhausted, Refund gives it back if the request fails.
 type PerIPLimiter interface {
-	IsRequestAllowed(ip net.IP) bool
-	Increment(ip net.IP)
+	Reserve(ip net.IP) bool
+	Refund(ip net.IP)
 }
@@ -6,9 +6,10 @@ import (
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/CoreumFoundation/coreum-tools/pkg/logger"
-	"github.com/CoreumFoundation/coreum-tools/pkg/must"
 )
 
 // Re-export logger vars for convenience.
@@ -45,13 +46,30 @@ func ConfigureWithCLI(defaultConfig logger.Config) (logger.Config, func(*pflag.F
 
 	_ = flags.Parse(os.Args[1:])
 
-	defaultConfig.Format = logger.Format(must.String(flags.GetString("log-format")))
-	defaultConfig.Verbose = must.Bool(flags.GetBool("verbose"))
-	if !validFormats[defaultConfig.Format] {
-		panic(errors.Errorf("incorrect logging format %s", defaultConfig.Format))
+	config, err := ApplyFlags(defaultConfig, flags)
+	if err != nil {
+		panic(err)
 	}
+	return config, newFlagRegister(flags, "help")
+}
 
-	return defaultConfig, newFlagRegister(flags, "help")
+// ApplyFlags updates the logger configuration with the values of the flags registered by ConfigureWithCLI, so
+// the values set by the config file or env vars are applied as well.
+func ApplyFlags(config logger.Config, flagSet *pflag.FlagSet) (logger.Config, error) {
+	format, err := flagSet.GetString("log-format")
+	if err != nil {
+		return logger.Config{}, errors.WithStack(err)
+	}
+	verbose, err := flagSet.GetBool("verbose")
+	if err != nil {
+		return logger.Config{}, errors.WithStack(err)
+	}
+	config.Format = logger.Format(format)
+	config.Verbose = verbose
+	if !validFormats[config.Format] {
+		return logger.Config{}, errors.Errorf("incorrect logging format %s", config.Format)
+	}
+	return config, nil
 }
 
 var validFormats = map[logger.Format]bool{
@@ -59,3 +77,30 @@ var validFormats = map[logger.Format]bool{
 	logger.FormatJSON:    true,
 	logger.FormatYAML:    true,
 }
+
+// NewWithLevel creates the logger the same way as New, but returns its level as well, so the verbosity might be
+// changed while running.
+func NewWithLevel(config Config) (*zap.Logger, zap.AtomicLevel) {
+	level := zap.NewAtomicLevelAt(Level(config))
+	cfg := zap.Config{
+		Level:            level,
+		Development:      true,
+		Encoding:         string(config.Format),
+		EncoderConfig:    logger.EncoderConfig,
+		OutputPaths:      []string{"stderr"},
+		ErrorOutputPaths: []string{"stderr"},
+	}
+	log, err := cfg.Build()
+	if err != nil {
+		panic(err)
+	}
+	return log, level
+}
+
+// Level returns the level of the logger created for the config.
+func Level(config Config) zapcore.Level {
+	if config.Verbose {
+		return zap.DebugLevel
+	}
+	return zap.InfoLevel
+}
@@ -0,0 +1,131 @@
+package mnemonic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/pem"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrDecryptionFailed is returned if the file can't be decrypted, usually because the passphrase is wrong.
+var ErrDecryptionFailed = errors.New("unable to decrypt mnemonics, wrong passphrase or corrupted file")
+
+const (
+	blockType = "FAUCET ENCRYPTED MNEMONIC"
+
+	headerKDF  = "KDF"
+	headerN    = "Scrypt-N"
+	headerR    = "Scrypt-R"
+	headerP    = "Scrypt-P"
+	headerSalt = "Salt"
+
+	kdfScrypt = "scrypt"
+	saltSize  = 16
+	keySize   = 32
+)
+
+// scrypt parameters used to encrypt new files, decryption uses the ones stored in the file.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// IsEncrypted tells if the data is the encrypted mnemonic file.
+func IsEncrypted(data []byte) bool {
+	block, _ := pem.Decode(data)
+	return block != nil && block.Type == blockType
+}
+
+// Encrypt encrypts the content of the mnemonic file with the key derived from the passphrase using scrypt.
+// The result is AES-GCM ciphertext armored as PEM block.
+func Encrypt(plaintext, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := newAEAD(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	block := &pem.Block{
+		Type: blockType,
+		Headers: map[string]string{
+			headerKDF:  kdfScrypt,
+			headerN:    strconv.Itoa(scryptN),
+			headerR:    strconv.Itoa(scryptR),
+			headerP:    strconv.Itoa(scryptP),
+			headerSalt: hex.EncodeToString(salt),
+		},
+		Bytes: aead.Seal(nonce, nonce, plaintext, nil),
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// Decrypt decrypts the file produced by Encrypt.
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, errors.New("data is not an encrypted mnemonic file")
+	}
+	if block.Headers[headerKDF] != kdfScrypt {
+		return nil, errors.Errorf("unsupported key derivation function %q", block.Headers[headerKDF])
+	}
+
+	var params [3]int
+	for i, header := range []string{headerN, headerR, headerP} {
+		var err error
+		params[i], err = strconv.Atoi(block.Headers[header])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s header", header)
+		}
+	}
+	salt, err := hex.DecodeString(block.Headers[headerSalt])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s header", headerSalt)
+	}
+
+	aead, err := newAEAD(passphrase, salt, params[0], params[1], params[2])
+	if err != nil {
+		return nil, err
+	}
+	if len(block.Bytes) < aead.NonceSize() {
+		return nil, errors.WithStack(ErrDecryptionFailed)
+	}
+	nonce, ciphertext := block.Bytes[:aead.NonceSize()], block.Bytes[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.WithStack(ErrDecryptionFailed)
+	}
+	return plaintext, nil
+}
+
+func newAEAD(passphrase, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, keySize)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to derive the key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return aead, nil
+}
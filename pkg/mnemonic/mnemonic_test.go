@@ -0,0 +1,31 @@
+package mnemonic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	requireT := require.New(t)
+
+	plaintext := []byte("mnemonic one\nmnemonic two\n")
+	encrypted, err := Encrypt(plaintext, []byte("passphrase"))
+	requireT.NoError(err)
+	requireT.True(IsEncrypted(encrypted))
+	requireT.False(IsEncrypted(plaintext))
+	requireT.NotContains(string(encrypted), "mnemonic one")
+
+	decrypted, err := Decrypt(encrypted, []byte("passphrase"))
+	requireT.NoError(err)
+	requireT.Equal(plaintext, decrypted)
+
+	_, err = Decrypt(encrypted, []byte("wrong"))
+	requireT.ErrorIs(err, ErrDecryptionFailed)
+
+	_, err = Decrypt(plaintext, []byte("passphrase"))
+	requireT.Error(err)
+
+	_, err = Encrypt(plaintext, nil)
+	requireT.Error(err)
+}
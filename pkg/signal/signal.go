@@ -24,3 +24,28 @@ func TerminateSignal(ctx context.Context) context.Context {
 	}()
 	return ctx
 }
+
+// ReloadSignal returns a channel receiving a value each time SIGHUP is received by the application, until ctx
+// is cancelled. Signals received while the previous one is still pending are merged.
+func ReloadSignal(ctx context.Context) <-chan struct{} {
+	log := logger.Get(ctx)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	reloadChan := make(chan struct{}, 1)
+	go func() {
+		defer signal.Stop(sigChan)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case s := <-sigChan:
+				log.Info("Received signal", zap.Stringer("signal", s))
+				select {
+				case reloadChan <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return reloadChan
+}